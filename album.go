@@ -0,0 +1,139 @@
+// Copyright 2014, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package itl
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// VariousArtists is the AlbumArtist given to compilation albums which don't have one set.
+const VariousArtists = "Various Artists"
+
+// Album is a group of tracks which make up an album.  iTunes does not model albums
+// directly, see Library.Albums.
+type Album struct {
+	Title       string
+	AlbumArtist string
+	Year        int
+
+	// Tracks is ordered by DiscNumber and then TrackNumber.
+	Tracks []Track
+
+	// TotalTime is the total duration of the tracks.
+	TotalTime time.Duration
+}
+
+// albumKey identifies an album.
+type albumKey struct {
+	artist, title string
+}
+
+// albumKey returns the key used to group t into an album.
+func (t Track) albumKey() albumKey {
+	k := albumKey{artist: t.AlbumArtist, title: t.Album}
+	if k.artist == "" && !t.Compilation {
+		k.artist = t.Artist
+	}
+	return k
+}
+
+// AlbumKey returns an opaque string identifying the album of the track, as grouped by
+// Library.Albums.
+func (t Track) AlbumKey() string {
+	k := t.albumKey()
+	return k.artist + "\x00" + k.title
+}
+
+// Albums groups the tracks in the library into albums.  Tracks are grouped by album name
+// and AlbumArtist (or Artist when AlbumArtist is empty).  Compilation tracks are grouped
+// by album name and AlbumArtist only, so that compilations don't fragment per-artist; when
+// they have no AlbumArtist the Album is given VariousArtists.  The Year of an album is the
+// first non-zero Year of its tracks.  Albums are ordered by AlbumArtist and then Title.
+func (l Library) Albums() []Album {
+	m := make(map[albumKey]*Album)
+	var albums []*Album
+	for _, t := range l.sortedTracks() {
+		k := t.albumKey()
+		a, ok := m[k]
+		if !ok {
+			a = &Album{Title: t.Album, AlbumArtist: k.artist}
+			if a.AlbumArtist == "" && t.Compilation {
+				a.AlbumArtist = VariousArtists
+			}
+			m[k] = a
+			albums = append(albums, a)
+		}
+		a.Tracks = append(a.Tracks, t)
+		a.TotalTime += t.Duration()
+	}
+
+	result := make([]Album, len(albums))
+	for i, a := range albums {
+		sort.SliceStable(a.Tracks, func(i, j int) bool {
+			x, y := a.Tracks[i], a.Tracks[j]
+			if x.DiscNumber != y.DiscNumber {
+				return x.DiscNumber < y.DiscNumber
+			}
+			return x.TrackNumber < y.TrackNumber
+		})
+		for _, t := range a.Tracks {
+			if t.Year != 0 {
+				a.Year = t.Year
+				break
+			}
+		}
+		result[i] = *a
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		x, y := result[i], result[j]
+		if c := strings.Compare(strings.ToLower(x.AlbumArtist), strings.ToLower(y.AlbumArtist)); c != 0 {
+			return c < 0
+		}
+		return strings.ToLower(x.Title) < strings.ToLower(y.Title)
+	})
+	return result
+}
+
+// AlbumRatings returns the effective rating (0-100) of each album in the library, keyed by
+// Track.AlbumKey.  An explicit album rating (an AlbumRating which isn't AlbumRatingComputed)
+// on any of its tracks is used if present, otherwise the rating is the average of the
+// explicit (not RatingComputed) ratings of its rated tracks, as iTunes computes it.  Albums
+// without any ratings are omitted.
+func (l Library) AlbumRatings() map[string]int {
+	type rating struct {
+		explicit         int
+		hasExplicit      bool
+		sum, ratedTracks int
+	}
+	ratings := make(map[string]*rating)
+	for _, t := range l.sortedTracks() {
+		k := t.AlbumKey()
+		r, ok := ratings[k]
+		if !ok {
+			r = &rating{}
+			ratings[k] = r
+		}
+		if t.AlbumRating != 0 && !t.AlbumRatingComputed {
+			r.explicit, r.hasExplicit = t.AlbumRating, true
+		}
+		if t.Rating != 0 && !t.RatingComputed {
+			r.sum += t.Rating
+			r.ratedTracks++
+		}
+	}
+
+	m := make(map[string]int, len(ratings))
+	for k, r := range ratings {
+		switch {
+		case r.hasExplicit:
+			m[k] = r.explicit
+		case r.ratedTracks > 0:
+			m[k] = (r.sum + r.ratedTracks/2) / r.ratedTracks
+		}
+	}
+	return m
+}
@@ -0,0 +1,75 @@
+// Copyright 2014, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package itl
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/url"
+	"path"
+	"strconv"
+)
+
+// anonymousMusicFolder is the MusicFolder given to anonymized libraries.
+const anonymousMusicFolder = "file://localhost/Music/"
+
+// Anonymize returns a copy of the library with personal data removed, so that it can be
+// shared (for instance to reproduce a bug) without revealing file paths or identifiers:
+//
+//   - persistent IDs (of the library, tracks and playlists, and ParentPersistentID) are
+//     replaced by hashes, which are still 16 hex digits and consistent, so references
+//     between playlists are preserved and the same ID is always replaced by the same hash
+//   - Comments are cleared
+//   - MusicFolder is replaced by file://localhost/Music/, and each file Location by a file
+//     in that folder named by the TrackID, keeping the file extension; other locations
+//     (such as streams) are replaced by <scheme>://localhost/<TrackID>
+//
+// Everything else (track and playlist counts, metadata, durations and playlist membership)
+// is unchanged.  Note that Extra values are copied as they are.
+func (l Library) Anonymize() Library {
+	a := l.Clone()
+	a.LibraryPersistentID = anonymousPersistentID(a.LibraryPersistentID)
+	if a.MusicFolder != "" {
+		a.MusicFolder = anonymousMusicFolder
+	}
+	for k, t := range a.Tracks {
+		t.PersistentID = anonymousPersistentID(t.PersistentID)
+		t.Comments = ""
+		t.Location = anonymousLocation(t)
+		a.Tracks[k] = t
+	}
+	for i := range a.Playlists {
+		p := &a.Playlists[i]
+		p.PlaylistPersistentID = anonymousPersistentID(p.PlaylistPersistentID)
+		p.ParentPersistentID = anonymousPersistentID(p.ParentPersistentID)
+	}
+	return a
+}
+
+// anonymousPersistentID returns a persistent ID derived from a hash of id,
+// or "" if id is empty.
+func anonymousPersistentID(id string) string {
+	if id == "" {
+		return ""
+	}
+	h := sha256.Sum256([]byte(id))
+	return fmt.Sprintf("%016X", h[:8])
+}
+
+// anonymousLocation returns the placeholder for the Location of t, or "" if it is empty.
+func anonymousLocation(t Track) string {
+	if t.Location == "" {
+		return ""
+	}
+	u, err := url.Parse(t.Location)
+	if err != nil || u.Scheme == "" {
+		return ""
+	}
+	id := strconv.Itoa(t.TrackID)
+	if u.Scheme != "file" {
+		return u.Scheme + "://localhost/" + id
+	}
+	return anonymousMusicFolder + id + url.PathEscape(path.Ext(u.Path))
+}
@@ -0,0 +1,22 @@
+// Copyright 2014, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package itl
+
+import "path/filepath"
+
+// HasArtwork returns true if the track has artwork.
+func (t Track) HasArtwork() bool {
+	return t.ArtworkCount > 0
+}
+
+// ArtworkCacheDir returns the directory in which iTunes conventionally caches the artwork
+// of the tracks in the library, given base, the iTunes folder containing the library (for
+// instance ~/Music/iTunes).  This is the "Album Artwork/Cache/<Library Persistent ID>"
+// directory under base.  The location and format of the cache is not documented by Apple
+// and varies between iTunes versions (newer versions of Music don't use it at all), so
+// the directory may not exist.
+func (l Library) ArtworkCacheDir(base string) string {
+	return filepath.Join(base, "Album Artwork", "Cache", l.LibraryPersistentID)
+}
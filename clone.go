@@ -0,0 +1,78 @@
+// Copyright 2014, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package itl
+
+// Clone returns a deep copy of the library: the Tracks map, Playlists and all the slices
+// and maps they contain are copied, so changes to the clone never affect l.
+func (l Library) Clone() Library {
+	c := l
+	c.Extra = cloneExtra(l.Extra)
+	if l.Tracks != nil {
+		c.Tracks = make(map[string]Track, len(l.Tracks))
+		for k, t := range l.Tracks {
+			c.Tracks[k] = t.clone()
+		}
+	}
+	if l.Playlists != nil {
+		c.Playlists = make([]Playlist, len(l.Playlists))
+		for i, p := range l.Playlists {
+			c.Playlists[i] = p.clone()
+		}
+	}
+	return c
+}
+
+// clone returns a deep copy of t.
+func (t Track) clone() Track {
+	t.Extra = cloneExtra(t.Extra)
+	return t
+}
+
+// clone returns a deep copy of p.
+func (p Playlist) clone() Playlist {
+	p.SmartInfo = cloneBytes(p.SmartInfo)
+	p.SmartCriteria = cloneBytes(p.SmartCriteria)
+	if p.PlaylistItems != nil {
+		p.PlaylistItems = append([]PlaylistItem{}, p.PlaylistItems...)
+	}
+	p.Extra = cloneExtra(p.Extra)
+	return p
+}
+
+func cloneBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	return append([]byte{}, b...)
+}
+
+// cloneExtra returns a deep copy of the Extra map m.
+func cloneExtra(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+	c := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		c[k] = cloneValue(v)
+	}
+	return c
+}
+
+// cloneValue returns a deep copy of a value decoded into an interface{}.
+func cloneValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return cloneExtra(v)
+	case []interface{}:
+		c := make([]interface{}, len(v))
+		for i, x := range v {
+			c[i] = cloneValue(x)
+		}
+		return c
+	case []byte:
+		return cloneBytes(v)
+	}
+	return v
+}
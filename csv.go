@@ -0,0 +1,86 @@
+// Copyright 2014, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package itl
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// DefaultCSVColumns are the columns written by WriteCSV when none are given.
+var DefaultCSVColumns = []string{"Name", "Artist", "Album", "Genre", "Year", "TotalTime", "PlayCount", "DateAdded"}
+
+// durationFields are the Track fields holding milliseconds, which WriteCSV writes
+// as durations.
+var durationFields = map[string]bool{
+	"TotalTime": true,
+	"StartTime": true,
+	"StopTime":  true,
+}
+
+var trackType = reflect.TypeOf(Track{})
+
+// WriteCSV writes the tracks in the library to w as CSV (RFC 4180), in ascending TrackID
+// order.  The first row is a header containing the column names, which are the names of
+// Track fields (e.g. "Name", "PlayCount", "DateAdded").  If columns is nil then
+// DefaultCSVColumns is used.  Times are written in RFC 3339 format (or left empty if zero)
+// and millisecond durations such as TotalTime are written as time.Duration strings.
+// Returns an error if a column does not name a Track field.
+func (l Library) WriteCSV(w io.Writer, columns []string) error {
+	if columns == nil {
+		columns = DefaultCSVColumns
+	}
+	idx := make([][]int, len(columns))
+	for i, c := range columns {
+		f, ok := trackType.FieldByName(c)
+		if !ok {
+			return fmt.Errorf("itl: unknown CSV column %q", c)
+		}
+		idx[i] = f.Index
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(columns); err != nil {
+		return err
+	}
+	record := make([]string, len(columns))
+	for _, t := range l.sortedTracks() {
+		v := reflect.ValueOf(t)
+		for i, c := range columns {
+			record[i] = csvValue(v.FieldByIndex(idx[i]), durationFields[c])
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvValue formats the Track field v for WriteCSV.
+func csvValue(v reflect.Value, duration bool) string {
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if duration {
+			return (time.Duration(v.Int()) * time.Millisecond).String()
+		}
+		return strconv.FormatInt(v.Int(), 10)
+	}
+	if t, ok := v.Interface().(time.Time); ok {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	}
+	return fmt.Sprint(v.Interface())
+}
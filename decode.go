@@ -0,0 +1,706 @@
+// Copyright 2014, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package itl
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	libraryType = reflect.TypeOf(Library{})
+	extraType   = reflect.TypeOf(map[string]interface{}(nil))
+)
+
+// ErrNoPlist is returned by Decode when the input does not contain a plist.
+var ErrNoPlist = errors.New("itl: no plist element found")
+
+// Decoder reads and decodes iTunes XML (plist) data from an input stream.  Unlike
+// ReadFromXML, which reads the entire input into memory before parsing it, a Decoder
+// parses the input as it is read, so peak memory is roughly halved for large libraries.
+type Decoder struct {
+	// Options configures how the input is decoded.  It must not be changed
+	// during a call to Decode.
+	Options DecodeOptions
+
+	x      *xml.Decoder
+	fields map[reflect.Type]*structInfo
+
+	// path is the sequence of keys (and array indices) leading to the value
+	// being decoded.
+	path []string
+
+	dateErrors []DateError
+	warnings   []Warning
+
+	// libraryKeys records the top-level library keys seen, when Options.Strict is set.
+	libraryKeys map[string]bool
+
+	// onTrack, when set, is called for each track in the Tracks dict instead of
+	// storing them in the Library.
+	onTrack func(Track) error
+}
+
+// NewDecoder returns a new Decoder that reads from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{
+		x:      xml.NewDecoder(r),
+		fields: make(map[reflect.Type]*structInfo),
+	}
+}
+
+// Decode reads iTunes XML (plist) data from the underlying io.Reader
+// returning the resulting Library.
+func Decode(r io.Reader) (Library, error) {
+	var l Library
+	err := NewDecoder(r).Decode(&l)
+	return l, err
+}
+
+// DecodeWithOptions is like Decode, but decodes using the given options.
+func DecodeWithOptions(r io.Reader, opts DecodeOptions) (Result, error) {
+	d := NewDecoder(r)
+	d.Options = opts
+	var res Result
+	err := d.Decode(&res.Library)
+	res.DateErrors = d.DateErrors()
+	res.Warnings = d.Warnings()
+	return res, err
+}
+
+// ReadFromReaderAt decodes the first size bytes of r using the given options, returning
+// the resulting Library (use DecodeWithOptions for date errors and warnings).  This is
+// convenient for memory-mapped files, and r may be read concurrently by other callers.
+// Note that a plist can't be navigated without parsing it (there is no index of where the
+// Tracks dict ends), so r is read sequentially from the start: options such as SkipTracks
+// reduce the work done on skipped values but can't seek past them.
+func ReadFromReaderAt(r io.ReaderAt, size int64, opts DecodeOptions) (Library, error) {
+	res, err := DecodeWithOptions(io.NewSectionReader(r, 0, size), opts)
+	return res.Library, err
+}
+
+// DateErrors returns the dates which could not be parsed by the last call to Decode.  It is
+// always empty when Options.OnDateError is DateErrorFail.
+func (d *Decoder) DateErrors() []DateError {
+	return d.dateErrors
+}
+
+// Warnings returns the problems found by the last call to Decode which did not stop it
+// from decoding.
+func (d *Decoder) Warnings() []Warning {
+	return d.warnings
+}
+
+// Decode reads the plist from its input and stores it in l.
+func (d *Decoder) Decode(l *Library) error {
+	d.path = d.path[:0]
+	d.dateErrors = nil
+	d.warnings = nil
+	d.libraryKeys = nil
+	start, err := d.root()
+	if err != nil {
+		return err
+	}
+	if !d.Options.Strict {
+		return d.decodeValue(start, reflect.ValueOf(l).Elem())
+	}
+
+	if start.Name.Local != "dict" {
+		return fmt.Errorf("itl: root of plist is <%s>, not an iTunes library <dict>", start.Name.Local)
+	}
+	d.libraryKeys = make(map[string]bool)
+	if err := d.decodeValue(start, reflect.ValueOf(l).Elem()); err != nil {
+		return err
+	}
+	for _, k := range strictLibraryKeys {
+		if !d.libraryKeys[k] {
+			return fmt.Errorf("itl: plist is not an iTunes library: missing %q", k)
+		}
+	}
+	return nil
+}
+
+// strictLibraryKeys are the top-level keys which must be present when Options.Strict is set.
+// iTunes always writes Major Version first.
+var strictLibraryKeys = []string{"Major Version", "Tracks"}
+
+// DecodeTracks reads iTunes XML (plist) data from r, calling fn for each track as it is
+// parsed rather than building the Tracks map, so memory use does not grow with the number
+// of tracks.  The top-level library values which precede the tracks are parsed (and
+// discarded) as usual, and playlists are skipped.  If fn returns a non-nil error then
+// decoding stops and the error is returned.
+func DecodeTracks(r io.Reader, fn func(Track) error) error {
+	d := NewDecoder(r)
+	d.Options.SkipPlaylists = true
+	var l Library
+	return d.DecodeTracks(&l, fn)
+}
+
+// DecodeTracks is like Decode, but calls fn for each track as it is parsed rather than storing
+// it in l.Tracks, which is left nil.  All other values (including playlists) are stored in l.
+// If fn returns a non-nil error then decoding stops and the error is returned.
+func (d *Decoder) DecodeTracks(l *Library, fn func(Track) error) error {
+	d.onTrack = fn
+	defer func() { d.onTrack = nil }()
+	return d.Decode(l)
+}
+
+// root advances the input to the root value of the plist, returning its start element.
+func (d *Decoder) root() (xml.StartElement, error) {
+	inPlist := false
+	for {
+		tok, err := d.x.RawToken()
+		if err == io.EOF {
+			return xml.StartElement{}, ErrNoPlist
+		}
+		if err != nil {
+			return xml.StartElement{}, err
+		}
+		if se, ok := tok.(xml.StartElement); ok {
+			if inPlist {
+				return se, nil
+			}
+			if se.Name.Local != "plist" {
+				return xml.StartElement{}, ErrNoPlist
+			}
+			inPlist = true
+		}
+	}
+}
+
+// next returns the next start or end element from the input, skipping
+// character data, comments and other tokens.
+func (d *Decoder) next() (xml.Token, error) {
+	for {
+		tok, err := d.x.RawToken()
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			return tok, nil
+		case xml.EndElement:
+			return tok, nil
+		}
+	}
+}
+
+// text returns the character data of the current element, consuming its end element.
+func (d *Decoder) text() (string, error) {
+	var s []string
+	for {
+		tok, err := d.x.RawToken()
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return "", err
+		}
+		switch tok := tok.(type) {
+		case xml.CharData:
+			s = append(s, string(tok))
+		case xml.StartElement:
+			return "", fmt.Errorf("itl: unexpected <%s> in text", tok.Name.Local)
+		case xml.EndElement:
+			return strings.Join(s, ""), nil
+		}
+	}
+}
+
+// skip consumes the remainder of the current element.
+func (d *Decoder) skip() error {
+	depth := 1
+	for depth > 0 {
+		tok, err := d.next()
+		if err != nil {
+			return err
+		}
+		switch tok.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			depth--
+		}
+	}
+	return nil
+}
+
+// structInfo describes how plist keys are decoded into a struct type.
+type structInfo struct {
+	// fields maps plist keys to field indices.
+	fields map[string]int
+
+	// extra is the index of the Extra field, or -1 if there isn't one.
+	extra int
+
+	// keepFalse are the indices of the bool fields which are true when their key is
+	// missing, see keepFalse.
+	keepFalse []int
+}
+
+// structInfo returns the structInfo for the struct type t.
+func (d *Decoder) structInfo(t reflect.Type) *structInfo {
+	if si, ok := d.fields[t]; ok {
+		return si
+	}
+	si := &structInfo{
+		fields: make(map[string]int, t.NumField()),
+		extra:  -1,
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		if f.Name == "Extra" && f.Type == extraType {
+			si.extra = i
+			continue
+		}
+		if k := fieldKey(f); k != "-" {
+			si.fields[k] = i
+		}
+		if keepFalse(f) {
+			si.keepFalse = append(si.keepFalse, i)
+		}
+	}
+	d.fields[t] = si
+	return si
+}
+
+// typeError returns an error for a plist element which can't be stored in v.
+func typeError(elem string, v reflect.Value) error {
+	return fmt.Errorf("itl: cannot decode <%s> into %s", elem, v.Type())
+}
+
+// decodeValue decodes the plist value starting with start into v.
+func (d *Decoder) decodeValue(start xml.StartElement, v reflect.Value) error {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+
+	switch start.Name.Local {
+	case "dict":
+		return d.decodeDict(v)
+
+	case "array":
+		return d.decodeArray(v)
+
+	case "true", "false":
+		b := start.Name.Local == "true"
+		if err := d.skip(); err != nil {
+			return err
+		}
+		switch v.Kind() {
+		case reflect.Bool:
+			v.SetBool(b)
+		case reflect.Interface:
+			v.Set(reflect.ValueOf(b))
+		default:
+			return typeError(start.Name.Local, v)
+		}
+		return nil
+	}
+
+	s, err := d.text()
+	if err != nil {
+		return err
+	}
+	return d.setScalar(start.Name.Local, s, v)
+}
+
+// pathString returns the current path as a string.
+func (d *Decoder) pathString() string {
+	return strings.Join(d.path, "/")
+}
+
+// dateError handles a <date> with text s which could not be parsed, according to
+// Options.OnDateError.  DateErrorSkip leaves v unset, which only differs from
+// DateErrorZero when v is an interface (an Extra, map or array value): a time.Time
+// field is already zero.
+func (d *Decoder) dateError(s string, err error, v reflect.Value) error {
+	if d.Options.OnDateError == DateErrorFail {
+		return fmt.Errorf("itl: %s: %v", d.pathString(), err)
+	}
+	d.dateErrors = append(d.dateErrors, DateError{
+		Path:  d.pathString(),
+		Value: s,
+		Err:   err,
+	})
+	if d.Options.OnDateError == DateErrorZero {
+		switch {
+		case v.Type() == timeType:
+			v.Set(reflect.ValueOf(time.Time{}))
+		case v.Kind() == reflect.Interface:
+			v.Set(reflect.ValueOf(time.Time{}))
+		}
+	}
+	return nil
+}
+
+// setScalar parses the text s of the plist element elem into v.
+func (d *Decoder) setScalar(elem, s string, v reflect.Value) error {
+	switch elem {
+	case "string", "key":
+		switch v.Kind() {
+		case reflect.String:
+			v.SetString(s)
+		case reflect.Interface:
+			v.Set(reflect.ValueOf(s))
+		default:
+			return typeError(elem, v)
+		}
+
+	case "integer":
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			n, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+			if err != nil {
+				return err
+			}
+			v.SetInt(n)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			n, err := strconv.ParseUint(strings.TrimSpace(s), 10, 64)
+			if err != nil {
+				return err
+			}
+			v.SetUint(n)
+		case reflect.Float32, reflect.Float64:
+			n, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+			if err != nil {
+				return err
+			}
+			v.SetFloat(n)
+		case reflect.Interface:
+			n, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+			if err != nil {
+				return err
+			}
+			v.Set(reflect.ValueOf(n))
+		default:
+			return typeError(elem, v)
+		}
+
+	case "real":
+		n, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil {
+			return err
+		}
+		switch v.Kind() {
+		case reflect.Float32, reflect.Float64:
+			v.SetFloat(n)
+		case reflect.Interface:
+			v.Set(reflect.ValueOf(n))
+		default:
+			return typeError(elem, v)
+		}
+
+	case "date":
+		t, err := time.Parse(time.RFC3339, strings.TrimSpace(s))
+		if err != nil {
+			return d.dateError(s, err, v)
+		}
+		switch {
+		case v.Type() == timeType:
+			v.Set(reflect.ValueOf(t))
+		case v.Kind() == reflect.Interface:
+			v.Set(reflect.ValueOf(t))
+		default:
+			return typeError(elem, v)
+		}
+
+	case "data":
+		b, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(s), ""))
+		if err != nil {
+			return err
+		}
+		switch {
+		case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+			v.SetBytes(b)
+		case v.Kind() == reflect.Interface:
+			v.Set(reflect.ValueOf(b))
+		default:
+			return typeError(elem, v)
+		}
+
+	default:
+		return fmt.Errorf("itl: unknown plist element <%s>", elem)
+	}
+	return nil
+}
+
+// decodeDict decodes the entries of a <dict> into v, which must be a struct, a map
+// with string keys or an empty interface.
+func (d *Decoder) decodeDict(v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Struct:
+		for _, i := range d.structInfo(v.Type()).keepFalse {
+			v.Field(i).SetBool(true)
+		}
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return typeError("dict", v)
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+	case reflect.Interface:
+		m := make(map[string]interface{})
+		if err := d.decodeDict(reflect.ValueOf(m)); err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(m))
+		return nil
+	default:
+		return typeError("dict", v)
+	}
+
+	for {
+		k, ok, err := d.key()
+		if err != nil || !ok {
+			return err
+		}
+		start, err := d.valueStart()
+		if err != nil {
+			return err
+		}
+		d.path = append(d.path, k)
+		err = d.decodeEntry(k, start, v)
+		d.path = d.path[:len(d.path)-1]
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// decodeEntry decodes the value for key k in a <dict>, which starts with start, into v.
+func (d *Decoder) decodeEntry(k string, start xml.StartElement, v reflect.Value) error {
+	if v.Kind() == reflect.Map {
+		elem := reflect.New(v.Type().Elem()).Elem()
+		if err := d.decodeValue(start, elem); err != nil {
+			return err
+		}
+		if elem.Kind() != reflect.Interface || !elem.IsNil() {
+			v.SetMapIndex(reflect.ValueOf(k).Convert(v.Type().Key()), elem)
+		}
+		return nil
+	}
+
+	if v.Type() == libraryType {
+		handled, err := d.libraryKey(k, start, v.Addr().Interface().(*Library))
+		if handled || err != nil {
+			return err
+		}
+	}
+
+	si := d.structInfo(v.Type())
+	i, ok := si.fields[k]
+	if ok {
+		return d.decodeValue(start, v.Field(i))
+	}
+	if si.extra < 0 {
+		return d.skip()
+	}
+	var x interface{}
+	if err := d.decodeValue(start, reflect.ValueOf(&x).Elem()); err != nil {
+		return err
+	}
+	if x != nil {
+		extra := v.Field(si.extra)
+		if extra.IsNil() {
+			extra.Set(reflect.MakeMap(extraType))
+		}
+		extra.SetMapIndex(reflect.ValueOf(k), reflect.ValueOf(x))
+	}
+	return nil
+}
+
+// libraryKey handles the top-level library key k whose value starts with start,
+// returning true if the value has been consumed.
+func (d *Decoder) libraryKey(k string, start xml.StartElement, l *Library) (bool, error) {
+	if d.libraryKeys != nil {
+		if len(d.libraryKeys) == 0 && k != strictLibraryKeys[0] {
+			return false, fmt.Errorf("itl: plist is not an iTunes library: first key is %q, not %q", k, strictLibraryKeys[0])
+		}
+		d.libraryKeys[k] = true
+	}
+
+	switch {
+	case k == "Tracks" && d.onTrack != nil:
+		return true, d.decodeTracks(start, func(_ string, t Track) error {
+			return d.onTrack(t)
+		})
+
+	case k == "Tracks" && d.Options.SkipTracks:
+		return true, d.skip()
+
+	case k == "Tracks" && (d.Options.DetectDuplicateTracks || d.Options.CanonicalTrackKeys || d.Options.Progress != nil):
+		if l.Tracks == nil {
+			l.Tracks = make(map[string]Track)
+		}
+		return true, d.decodeTracks(start, func(k string, t Track) error {
+			l.Tracks[k] = t
+			return nil
+		})
+
+	case k == "Playlists" && d.Options.SkipPlaylists:
+		return true, d.skip()
+	}
+	return false, nil
+}
+
+// decodeTracks decodes each track in the Tracks dict, which starts with start, and
+// passes it to fn along with its key.  Keys are canonicalized and checked for duplicates
+// according to d.Options, and progress is reported to d.Options.Progress.
+func (d *Decoder) decodeTracks(start xml.StartElement, fn func(string, Track) error) error {
+	if start.Name.Local != "dict" {
+		return fmt.Errorf("itl: expected <dict> for Tracks, got <%s>", start.Name.Local)
+	}
+	var seen map[string]bool
+	if d.Options.DetectDuplicateTracks {
+		seen = make(map[string]bool)
+	}
+	n := 0
+	for {
+		k, ok, err := d.key()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			if d.Options.Progress != nil {
+				d.Options.Progress(n)
+			}
+			return nil
+		}
+		ts, err := d.valueStart()
+		if err != nil {
+			return err
+		}
+		var t Track
+		d.path = append(d.path, k)
+		err = d.decodeValue(ts, reflect.ValueOf(&t).Elem())
+		if err == nil && d.Options.CanonicalTrackKeys {
+			k = canonicalTrackKey(k)
+		}
+		if err == nil && seen != nil {
+			if seen[k] {
+				d.warn("duplicate track ID %s", k)
+			}
+			seen[k] = true
+		}
+		d.path = d.path[:len(d.path)-1]
+		if err != nil {
+			return err
+		}
+		if err := fn(k, t); err != nil {
+			return err
+		}
+		n++
+		if d.Options.Progress != nil && n%ProgressInterval == 0 {
+			d.Options.Progress(n)
+		}
+	}
+}
+
+// canonicalTrackKey returns the canonical form of the Tracks key k: the decimal form
+// of its integer value with surrounding space and leading zeros removed.  Keys which
+// aren't integers are returned unchanged.
+func canonicalTrackKey(k string) string {
+	n, err := strconv.Atoi(strings.TrimSpace(k))
+	if err != nil {
+		return k
+	}
+	return strconv.Itoa(n)
+}
+
+// warn records a Warning for the current path.
+func (d *Decoder) warn(format string, args ...interface{}) {
+	d.warnings = append(d.warnings, Warning{
+		Path:    d.pathString(),
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// key reads the next <key> in a <dict>, returning false if the end of the <dict>
+// was reached instead.
+func (d *Decoder) key() (string, bool, error) {
+	tok, err := d.next()
+	if err != nil {
+		return "", false, err
+	}
+	switch tok := tok.(type) {
+	case xml.EndElement:
+		return "", false, nil
+	case xml.StartElement:
+		if tok.Name.Local != "key" {
+			return "", false, fmt.Errorf("itl: expected <key> in <dict>, got <%s>", tok.Name.Local)
+		}
+	}
+	k, err := d.text()
+	return k, err == nil, err
+}
+
+// valueStart reads the start element of the value following a <key>.
+func (d *Decoder) valueStart() (xml.StartElement, error) {
+	tok, err := d.next()
+	if err != nil {
+		return xml.StartElement{}, err
+	}
+	start, ok := tok.(xml.StartElement)
+	if !ok {
+		return xml.StartElement{}, errors.New("itl: missing value for <key> in <dict>")
+	}
+	return start, nil
+}
+
+// decodeArray decodes the elements of an <array> into v, which must be a slice or
+// an empty interface.
+func (d *Decoder) decodeArray(v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Slice:
+		v.SetLen(0)
+	case reflect.Interface:
+		var a []interface{}
+		s := reflect.ValueOf(&a).Elem()
+		if err := d.decodeArray(s); err != nil {
+			return err
+		}
+		v.Set(s)
+		return nil
+	default:
+		return typeError("array", v)
+	}
+
+	for {
+		tok, err := d.next()
+		if err != nil {
+			return err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			return nil
+		}
+		elem := reflect.New(v.Type().Elem()).Elem()
+		d.path = append(d.path, strconv.Itoa(v.Len()))
+		err = d.decodeValue(start, elem)
+		d.path = d.path[:len(d.path)-1]
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.Append(v, elem))
+	}
+}
@@ -0,0 +1,500 @@
+// Copyright 2014, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package itl
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testLibraryXML is a fragment of a library written by iTunes.
+const testLibraryXML = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Major Version</key><integer>1</integer>
+	<key>Minor Version</key><integer>1</integer>
+	<key>Date</key><date>2014-06-22T10:38:11Z</date>
+	<key>Application Version</key><string>11.2.2</string>
+	<key>Features</key><integer>5</integer>
+	<key>Show Content Ratings</key><true/>
+	<key>Music Folder</key><string>file:///Users/david/Music/iTunes/iTunes%20Media/</string>
+	<key>Library Persistent ID</key><string>6C2D3B2F9D8E1A4B</string>
+	<key>Tracks</key>
+	<dict>
+		<key>1021</key>
+		<dict>
+			<key>Track ID</key><integer>1021</integer>
+			<key>Name</key><string>Rock &#38; Roll &lt;Live&gt;</string>
+			<key>Artist</key><string>Led Zeppelin</string>
+			<key>Album</key><string>How the West Was Won</string>
+			<key>Genre</key><string>Rock</string>
+			<key>Kind</key><string>AAC audio file</string>
+			<key>Size</key><integer>8130653</integer>
+			<key>Total Time</key><integer>225533</integer>
+			<key>Disc Number</key><integer>3</integer>
+			<key>Disc Count</key><integer>3</integer>
+			<key>Track Number</key><integer>4</integer>
+			<key>Track Count</key><integer>5</integer>
+			<key>Year</key><integer>2003</integer>
+			<key>Date Modified</key><date>2012-03-04T05:06:07Z</date>
+			<key>Date Added</key><date>2013-01-02T03:04:05Z</date>
+			<key>Bit Rate</key><integer>256</integer>
+			<key>Sample Rate</key><integer>44100</integer>
+			<key>Play Count</key><integer>12</integer>
+			<key>Play Date</key><integer>3485146090</integer>
+			<key>Play Date UTC</key><date>2014-06-09T08:08:10Z</date>
+			<key>Loved</key><true/>
+			<key>Persistent ID</key><string>2F8A34E5F6B21C20</string>
+			<key>Track Type</key><string>File</string>
+			<key>Location</key><string>file:///Users/david/Music/iTunes/iTunes%20Media/Music/Led%20Zeppelin/04%20Rock%20&#38;%20Roll.m4a</string>
+			<key>File Folder Count</key><integer>5</integer>
+			<key>Library Folder Count</key><integer>1</integer>
+			<key>Normalization</key><integer>1530</integer>
+		</dict>
+		<key>1023</key>
+		<dict>
+			<key>Track ID</key><integer>1023</integer>
+			<key>Name</key><string>"Heroes"</string>
+			<key>Artist</key><string>David Bowie</string>
+			<key>Kind</key><string>MPEG audio file</string>
+			<key>Total Time</key><integer>371000</integer>
+			<key>Date Added</key><date>2013-01-02T03:04:06Z</date>
+			<key>Compilation</key><true/>
+			<key>Persistent ID</key><string>2F8A34E5F6B21C22</string>
+			<key>Track Type</key><string>File</string>
+		</dict>
+	</dict>
+	<key>Playlists</key>
+	<array>
+		<dict>
+			<key>Name</key><string>Library</string>
+			<key>Master</key><true/>
+			<key>Playlist ID</key><integer>2304</integer>
+			<key>Playlist Persistent ID</key><string>8D7A1F2C3E4B5A69</string>
+			<key>Visible</key><false/>
+			<key>All Items</key><true/>
+			<key>Playlist Items</key>
+			<array>
+				<dict>
+					<key>Track ID</key><integer>1021</integer>
+				</dict>
+				<dict>
+					<key>Track ID</key><integer>1023</integer>
+				</dict>
+			</array>
+		</dict>
+		<dict>
+			<key>Name</key><string>Loved &amp; Recent</string>
+			<key>Playlist ID</key><integer>2390</integer>
+			<key>Playlist Persistent ID</key><string>8D7A1F2C3E4B5A70</string>
+			<key>All Items</key><true/>
+			<key>Smart Info</key>
+			<data>
+			AQEAAwAAAAIAAAAZAAAAAAAAAAcAAAAAAAAAAAAAAAAAAAAA
+			AAAAAAAAAAAAAAAAAAAAAA==
+			</data>
+			<key>Smart Order</key><dict/>
+		</dict>
+	</array>
+</dict>
+</plist>
+`
+
+func TestDecode(t *testing.T) {
+	l, err := ReadFromXML(strings.NewReader(testLibraryXML))
+	if err != nil {
+		t.Fatalf("ReadFromXML() error = %v", err)
+	}
+
+	if l.MajorVersion != 1 || l.MinorVersion != 1 || l.ApplicationVersion != "11.2.2" || !l.ShowContentRatings {
+		t.Errorf("ReadFromXML() library header = %d.%d %q %v", l.MajorVersion, l.MinorVersion, l.ApplicationVersion, l.ShowContentRatings)
+	}
+	if len(l.Tracks) != 2 {
+		t.Fatalf("len(Tracks) = %d, want 2", len(l.Tracks))
+	}
+
+	tr := l.Tracks["1021"]
+	if want := "Rock & Roll <Live>"; tr.Name != want {
+		t.Errorf("Name = %q, want %q", tr.Name, want)
+	}
+	if want := "file:///Users/david/Music/iTunes/iTunes%20Media/Music/Led%20Zeppelin/04%20Rock%20&%20Roll.m4a"; tr.Location != want {
+		t.Errorf("Location = %q, want %q", tr.Location, want)
+	}
+	if want := time.Date(2013, 1, 2, 3, 4, 5, 0, time.UTC); !tr.DateAdded.Equal(want) {
+		t.Errorf("DateAdded = %v, want %v", tr.DateAdded, want)
+	}
+	if tr.TrackID != 1021 || tr.Size != 8130653 || tr.PlayDate != 3485146090 || !tr.Loved {
+		t.Errorf("ReadFromXML() track = %+v", tr)
+	}
+	if got := tr.Extra["Normalization"]; got != int64(1530) {
+		t.Errorf("Extra[\"Normalization\"] = %#v, want int64(1530)", got)
+	}
+	if want := `"Heroes"`; l.Tracks["1023"].Name != want {
+		t.Errorf("Name = %q, want %q", l.Tracks["1023"].Name, want)
+	}
+
+	if len(l.Playlists) != 2 {
+		t.Fatalf("len(Playlists) = %d, want 2", len(l.Playlists))
+	}
+	p := l.Playlists[0]
+	if want := []PlaylistItem{{TrackID: 1021}, {TrackID: 1023}}; !reflect.DeepEqual(p.PlaylistItems, want) {
+		t.Errorf("PlaylistItems = %v, want %v", p.PlaylistItems, want)
+	}
+	if !p.Master || p.Visible {
+		t.Errorf("Master, Visible = %v, %v, want true, false", p.Master, p.Visible)
+	}
+	p = l.Playlists[1]
+	if want := "Loved & Recent"; p.Name != want {
+		t.Errorf("Name = %q, want %q", p.Name, want)
+	}
+	if len(p.SmartInfo) != 52 || p.SmartInfo[0] != 1 || p.SmartInfo[2] != 0 || p.SmartInfo[3] != 3 {
+		t.Errorf("SmartInfo = %v", p.SmartInfo)
+	}
+	if got, ok := p.Extra["Smart Order"].(map[string]interface{}); !ok || len(got) != 0 {
+		t.Errorf("Extra[\"Smart Order\"] = %#v, want empty dict", p.Extra["Smart Order"])
+	}
+}
+
+func TestDecodeEmptyDict(t *testing.T) {
+	l, err := ReadFromXML(strings.NewReader(`<plist version="1.0"><dict><key>Major Version</key><integer>1</integer><key>Tracks</key><dict/><key>Playlists</key><array/></dict></plist>`))
+	if err != nil {
+		t.Fatalf("ReadFromXML() error = %v", err)
+	}
+	if l.Tracks == nil || len(l.Tracks) != 0 {
+		t.Errorf("Tracks = %#v, want empty map", l.Tracks)
+	}
+	if len(l.Playlists) != 0 {
+		t.Errorf("Playlists = %#v, want none", l.Playlists)
+	}
+	if l.MajorVersion != 1 {
+		t.Errorf("MajorVersion = %d, want 1", l.MajorVersion)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"empty", "", "no plist element"},
+		{"not plist", `<html><body/></html>`, "no plist element"},
+		{"type", `<plist><dict><key>Major Version</key><string>one</string></dict></plist>`, "cannot decode <string> into int"},
+		{"integer", `<plist><dict><key>Major Version</key><integer>one</integer></dict></plist>`, "invalid syntax"},
+		{"root array", `<plist><array></array></plist>`, "cannot decode <array> into itl.Library"},
+		{"data", `<plist><dict><key>Playlists</key><array><dict><key>Smart Info</key><data>!!</data></dict></array></dict></plist>`, "illegal base64"},
+		{"date", `<plist><dict><key>Date</key><date>yesterday</date></dict></plist>`, `parsing time "yesterday"`},
+		{"missing key", `<plist><dict><string>x</string></dict></plist>`, "expected <key> in <dict>"},
+		{"missing value", `<plist><dict><key>Name</key></dict></plist>`, "missing value"},
+		{"unknown element", `<plist><dict><key>Major Version</key><uid>1</uid></dict></plist>`, "unknown plist element <uid>"},
+		{"unexpected EOF", `<plist><dict><key>Tracks</key><dict>`, io.ErrUnexpectedEOF.Error()},
+		{"malformed", `<plist><dict><key>Name</key><string>Rock & Roll</string></dict></plist>`, "XML syntax error"},
+	}
+
+	for _, tt := range tests {
+		_, err := Decode(strings.NewReader(tt.in))
+		if err == nil {
+			t.Errorf("%s: Decode() error = nil, want error", tt.name)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: Decode() error = %q, want it to contain %q", tt.name, err, tt.want)
+		}
+	}
+
+	if _, err := Decode(strings.NewReader("")); !errors.Is(err, ErrNoPlist) {
+		t.Errorf("Decode(\"\") error = %v, want ErrNoPlist", err)
+	}
+}
+
+func TestDecodeRoundTrip(t *testing.T) {
+	l, err := ReadFromXML(strings.NewReader(testLibraryXML))
+	if err != nil {
+		t.Fatalf("ReadFromXML() error = %v", err)
+	}
+	var buf bytes.Buffer
+	if err := WriteToXML(&buf, l); err != nil {
+		t.Fatalf("WriteToXML() error = %v", err)
+	}
+	got, err := ReadFromXML(&buf)
+	if err != nil {
+		t.Fatalf("ReadFromXML() of written library error = %v", err)
+	}
+	if !reflect.DeepEqual(got, l) {
+		t.Errorf("ReadFromXML(WriteToXML(l)) = %+v, want %+v", got, l)
+	}
+}
+
+func TestDecodeLibraryDate(t *testing.T) {
+	l, err := Decode(strings.NewReader(testLibraryXML))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if want := time.Date(2014, 6, 22, 10, 38, 11, 0, time.UTC); !l.Date.Equal(want) {
+		t.Errorf("Date = %v, want %v", l.Date, want)
+	}
+	if _, ok := l.Extra["Date"]; ok {
+		t.Errorf("Extra[\"Date\"] is set, want Date decoded into Library.Date")
+	}
+	if !l.Tracks["1021"].Date.IsZero() {
+		t.Errorf("Tracks[\"1021\"].Date = %v, want zero: the library Date must not be applied to tracks", l.Tracks["1021"].Date)
+	}
+}
+
+func TestDecodePlaylistItemID(t *testing.T) {
+	const in = `<plist version="1.0">
+<dict>
+	<key>Major Version</key><integer>1</integer>
+	<key>Playlists</key>
+	<array>
+		<dict>
+			<key>Name</key><string>Road Trip</string>
+			<key>Playlist ID</key><integer>2512</integer>
+			<key>Playlist Persistent ID</key><string>1A2B3C4D5E6F7081</string>
+			<key>All Items</key><true/>
+			<key>Playlist Items</key>
+			<array>
+				<dict>
+					<key>Track ID</key><integer>1021</integer>
+					<key>Playlist Item ID</key><integer>7001</integer>
+				</dict>
+				<dict>
+					<key>Track ID</key><integer>1021</integer>
+					<key>Playlist Item ID</key><integer>7002</integer>
+				</dict>
+				<dict>
+					<key>Track ID</key><integer>1023</integer>
+				</dict>
+			</array>
+		</dict>
+	</array>
+</dict>
+</plist>
+`
+	l, err := Decode(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	want := []PlaylistItem{
+		{TrackID: 1021, PlaylistItemID: 7001},
+		{TrackID: 1021, PlaylistItemID: 7002},
+		{TrackID: 1023},
+	}
+	if got := l.Playlists[0].PlaylistItems; !reflect.DeepEqual(got, want) {
+		t.Errorf("PlaylistItems = %v, want %v", got, want)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteToXML(&buf, l); err != nil {
+		t.Fatalf("WriteToXML() error = %v", err)
+	}
+	if n := strings.Count(buf.String(), "<key>Playlist Item ID</key>"); n != 2 {
+		t.Errorf("WriteToXML() wrote %d Playlist Item ID keys, want 2", n)
+	}
+}
+
+func TestDecodeTrackDate(t *testing.T) {
+	const in = `<plist version="1.0">
+<dict>
+	<key>Tracks</key>
+	<dict>
+		<key>2040</key>
+		<dict>
+			<key>Track ID</key><integer>2040</integer>
+			<key>Name</key><string>Voice Memo 12</string>
+			<key>Date Modified</key><date>2015-02-03T04:05:06Z</date>
+			<key>Date Added</key><date>2015-02-03T04:06:00Z</date>
+			<key>Date</key><date>2015-02-01T18:30:00Z</date>
+		</dict>
+	</dict>
+</dict>
+</plist>
+`
+	l, err := Decode(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	tr := l.Tracks["2040"]
+	tests := []struct {
+		name string
+		got  time.Time
+		want time.Time
+	}{
+		{"Date Modified", tr.DateModified, time.Date(2015, 2, 3, 4, 5, 6, 0, time.UTC)},
+		{"Date Added", tr.DateAdded, time.Date(2015, 2, 3, 4, 6, 0, 0, time.UTC)},
+		{"Date", tr.Date, time.Date(2015, 2, 1, 18, 30, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		if !tt.got.Equal(tt.want) {
+			t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+	if len(tr.Extra) != 0 {
+		t.Errorf("Extra = %v, want none", tr.Extra)
+	}
+	if !l.Date.IsZero() {
+		t.Errorf("Library.Date = %v, want zero: the track Date must not be applied to the library", l.Date)
+	}
+}
+
+// benchmarkLibrary returns a library of n tracks, and n/500 playlists of 500 tracks each,
+// written as iTunes XML.
+func benchmarkLibrary(b *testing.B, n int) []byte {
+	l := Library{
+		MajorVersion:       1,
+		MinorVersion:       1,
+		ApplicationVersion: "12.9.5.5",
+		Tracks:             make(map[string]Track, n),
+	}
+	added := time.Date(2013, 1, 2, 3, 4, 5, 0, time.UTC)
+	for i := 1; i <= n; i++ {
+		l.Tracks[strconv.Itoa(i)] = Track{
+			TrackID:      i,
+			Name:         "Track " + strconv.Itoa(i),
+			Artist:       "Artist " + strconv.Itoa(i%500),
+			Album:        "Album " + strconv.Itoa(i%2000),
+			Genre:        "Rock",
+			Kind:         "AAC audio file",
+			Size:         8130653,
+			TotalTime:    225533,
+			TrackNumber:  i%12 + 1,
+			Year:         2003,
+			DateModified: added,
+			DateAdded:    added,
+			BitRate:      256,
+			SampleRate:   44100,
+			PersistentID: fmt.Sprintf("%016X", i),
+			TrackType:    "File",
+			Location:     "file:///Users/david/Music/iTunes/iTunes%20Media/Music/Track%20" + strconv.Itoa(i) + ".m4a",
+		}
+	}
+	for i := 0; i < n/500; i++ {
+		p := Playlist{Name: "Playlist " + strconv.Itoa(i), PlaylistID: n + i + 1}
+		for j := 1; j <= 500; j++ {
+			p.PlaylistItems = append(p.PlaylistItems, PlaylistItem{TrackID: i*500 + j})
+		}
+		l.Playlists = append(l.Playlists, p)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteToXML(&buf, l); err != nil {
+		b.Fatalf("WriteToXML() error = %v", err)
+	}
+	return buf.Bytes()
+}
+
+func BenchmarkDecode(b *testing.B) {
+	data := benchmarkLibrary(b, 10000)
+	benchmarks := []struct {
+		name string
+		opts DecodeOptions
+	}{
+		{"All", DecodeOptions{}},
+		{"SkipTracks", DecodeOptions{SkipTracks: true}},
+		{"SkipPlaylists", DecodeOptions{SkipPlaylists: true}},
+		{"SkipTracks+SkipPlaylists", DecodeOptions{SkipTracks: true, SkipPlaylists: true}},
+	}
+
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := DecodeWithOptions(bytes.NewReader(data), bm.opts); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeDateErrorPolicy(t *testing.T) {
+	const in = `<plist version="1.0">
+<dict>
+	<key>Tracks</key>
+	<dict>
+		<key>1</key>
+		<dict>
+			<key>Track ID</key><integer>1</integer>
+			<key>Date Added</key><date>2013-02-30 25:00</date>
+			<key>Purchase Date</key><date>not a date</date>
+		</dict>
+	</dict>
+</dict>
+</plist>
+`
+	tests := []struct {
+		policy    DateErrorPolicy
+		wantExtra bool
+	}{
+		{DateErrorZero, true},
+		{DateErrorSkip, false},
+	}
+	for _, tt := range tests {
+		res, err := DecodeWithOptions(strings.NewReader(in), DecodeOptions{OnDateError: tt.policy})
+		if err != nil {
+			t.Fatalf("DecodeWithOptions(%v) error = %v", tt.policy, err)
+		}
+		tr := res.Library.Tracks["1"]
+		if !tr.DateAdded.IsZero() {
+			t.Errorf("%v: DateAdded = %v, want zero", tt.policy, tr.DateAdded)
+		}
+		v, ok := tr.Extra["Purchase Date"]
+		if ok != tt.wantExtra {
+			t.Errorf("%v: Extra[\"Purchase Date\"] present = %v, want %v", tt.policy, ok, tt.wantExtra)
+		}
+		if ok && !v.(time.Time).IsZero() {
+			t.Errorf("%v: Extra[\"Purchase Date\"] = %v, want zero time", tt.policy, v)
+		}
+		if len(res.DateErrors) != 2 {
+			t.Errorf("%v: len(DateErrors) = %d, want 2", tt.policy, len(res.DateErrors))
+		}
+	}
+
+	if _, err := Decode(strings.NewReader(in)); err == nil {
+		t.Errorf("Decode() with DateErrorFail error = nil, want error")
+	}
+}
+
+func TestDecodeStrict(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		// The input after the failing check is truncated, so these fail with an unexpected
+		// EOF unless the check is made before the rest is decoded.
+		{"root array", `<plist><array><dict>`, "root of plist is <array>"},
+		{"first key", `<plist><dict><key>Tracks</key><dict>`, `first key is "Tracks", not "Major Version"`},
+		{"missing tracks", `<plist><dict><key>Major Version</key><integer>1</integer><key>Playlists</key><array/></dict></plist>`, `missing "Tracks"`},
+	}
+
+	for _, tt := range tests {
+		_, err := DecodeWithOptions(strings.NewReader(tt.in), DecodeOptions{Strict: true})
+		if err == nil {
+			t.Errorf("%s: DecodeWithOptions(Strict) error = nil, want error", tt.name)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: DecodeWithOptions(Strict) error = %q, want it to contain %q", tt.name, err, tt.want)
+		}
+	}
+
+	res, err := DecodeWithOptions(strings.NewReader(testLibraryXML), DecodeOptions{Strict: true})
+	if err != nil {
+		t.Fatalf("DecodeWithOptions(Strict) error = %v", err)
+	}
+	if len(res.Library.Tracks) != 2 {
+		t.Errorf("len(Tracks) = %d, want 2", len(res.Library.Tracks))
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright 2014, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package itl
+
+import (
+	"reflect"
+	"sort"
+	"time"
+)
+
+// LibraryDiff describes the changes between two libraries, see Diff.
+type LibraryDiff struct {
+	Added    []Track
+	Removed  []Track
+	Modified []TrackDiff
+
+	AddedPlaylists   []Playlist
+	RemovedPlaylists []Playlist
+	PlaylistChanges  []PlaylistDiff
+}
+
+// TrackDiff describes the changes to a track.
+type TrackDiff struct {
+	PersistentID string
+	Changes      []FieldChange
+}
+
+// FieldChange is a change to the value of a field.  Field is the Go name of the field.
+type FieldChange struct {
+	Field    string
+	Old, New interface{}
+}
+
+// PlaylistDiff describes the changes to the membership of a playlist.  Tracks are
+// identified by their PersistentID.
+type PlaylistDiff struct {
+	PlaylistPersistentID string
+	Name                 string
+	Added                []string
+	Removed              []string
+}
+
+// Diff returns the changes made to the library old to produce the library new.  Tracks are
+// matched by PersistentID (and playlists by PlaylistPersistentID) rather than TrackID, as
+// iTunes renumbers TrackIDs each time it exports a library.  For the same reason changes to
+// TrackID are not reported.  Tracks without a PersistentID are ignored.  All slices in the
+// result are ordered by persistent ID.
+func Diff(old, new Library) LibraryDiff {
+	var d LibraryDiff
+	oldTracks := tracksByPersistentID(old)
+	newTracks := tracksByPersistentID(new)
+
+	for _, id := range sortedKeys(oldTracks) {
+		ot := oldTracks[id]
+		nt, ok := newTracks[id]
+		if !ok {
+			d.Removed = append(d.Removed, ot)
+			continue
+		}
+		if changes := trackChanges(ot, nt); len(changes) > 0 {
+			d.Modified = append(d.Modified, TrackDiff{PersistentID: id, Changes: changes})
+		}
+	}
+	for _, id := range sortedKeys(newTracks) {
+		if _, ok := oldTracks[id]; !ok {
+			d.Added = append(d.Added, newTracks[id])
+		}
+	}
+
+	oldPlaylists := playlistsByPersistentID(old)
+	newPlaylists := playlistsByPersistentID(new)
+	for _, id := range sortedKeys(oldPlaylists) {
+		op := oldPlaylists[id]
+		np, ok := newPlaylists[id]
+		if !ok {
+			d.RemovedPlaylists = append(d.RemovedPlaylists, op)
+			continue
+		}
+		om := old.playlistMembers(op)
+		nm := new.playlistMembers(np)
+		pd := PlaylistDiff{PlaylistPersistentID: id, Name: np.Name}
+		for _, tid := range sortedKeys(nm) {
+			if !om[tid] {
+				pd.Added = append(pd.Added, tid)
+			}
+		}
+		for _, tid := range sortedKeys(om) {
+			if !nm[tid] {
+				pd.Removed = append(pd.Removed, tid)
+			}
+		}
+		if len(pd.Added) > 0 || len(pd.Removed) > 0 {
+			d.PlaylistChanges = append(d.PlaylistChanges, pd)
+		}
+	}
+	for _, id := range sortedKeys(newPlaylists) {
+		if _, ok := oldPlaylists[id]; !ok {
+			d.AddedPlaylists = append(d.AddedPlaylists, newPlaylists[id])
+		}
+	}
+	return d
+}
+
+// trackChanges returns the fields (other than TrackID) which differ between a and b.
+func trackChanges(a, b Track) []FieldChange {
+	var changes []FieldChange
+	av, bv := reflect.ValueOf(a), reflect.ValueOf(b)
+	for i := 0; i < trackType.NumField(); i++ {
+		f := trackType.Field(i)
+		if f.PkgPath != "" || f.Name == "TrackID" {
+			continue
+		}
+		x, y := av.Field(i).Interface(), bv.Field(i).Interface()
+		if !valuesEqual(x, y) {
+			changes = append(changes, FieldChange{Field: f.Name, Old: x, New: y})
+		}
+	}
+	return changes
+}
+
+// valuesEqual reports whether the field values x and y are equal, comparing times
+// by instant rather than representation.
+func valuesEqual(x, y interface{}) bool {
+	if xt, ok := x.(time.Time); ok {
+		if yt, ok := y.(time.Time); ok {
+			return xt.Equal(yt)
+		}
+	}
+	return reflect.DeepEqual(x, y)
+}
+
+// tracksByPersistentID returns the tracks in l which have a PersistentID, keyed by it.
+func tracksByPersistentID(l Library) map[string]Track {
+	m := make(map[string]Track, len(l.Tracks))
+	for _, t := range l.Tracks {
+		if t.PersistentID != "" {
+			m[t.PersistentID] = t
+		}
+	}
+	return m
+}
+
+// playlistsByPersistentID returns the playlists in l which have a PlaylistPersistentID,
+// keyed by it.
+func playlistsByPersistentID(l Library) map[string]Playlist {
+	m := make(map[string]Playlist, len(l.Playlists))
+	for _, p := range l.Playlists {
+		if p.PlaylistPersistentID != "" {
+			m[p.PlaylistPersistentID] = p
+		}
+	}
+	return m
+}
+
+// playlistMembers returns the set of PersistentIDs of the tracks in p.
+func (l Library) playlistMembers(p Playlist) map[string]bool {
+	m := make(map[string]bool, len(p.PlaylistItems))
+	for _, t := range l.PlaylistTracks(p) {
+		if t.PersistentID != "" {
+			m[t.PersistentID] = true
+		}
+	}
+	return m
+}
+
+// sortedKeys returns the keys of the map m (which must have string keys) in ascending order.
+func sortedKeys(m interface{}) []string {
+	v := reflect.ValueOf(m)
+	keys := make([]string, 0, v.Len())
+	for _, k := range v.MapKeys() {
+		keys = append(keys, k.String())
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,102 @@
+// Copyright 2014, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package itl
+
+import (
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+)
+
+// WriteDJXML writes the library to w as iTunes XML for importing into DJ software (such as
+// Rekordbox or Serato), which only reads a subset of the keys and is strict about their form.
+// Only the following keys are written:
+//
+//	library   Major Version, Minor Version, Application Version, Music Folder
+//	tracks    Track ID, Name, Artist, Album, Total Time, BPM, Location
+//	playlists Name, Master, Playlist ID, Playlist Persistent ID, Parent Persistent ID,
+//	          Folder, Playlist Items (Track ID only)
+//
+// Locations are normalized to the file://localhost/ form with standard percent-encoding.
+// Tracks which aren't local files (such as streams, or tracks without a Location) are
+// omitted, along with the playlist items which reference them.
+func (l Library) WriteDJXML(w io.Writer) error {
+	dj := Library{
+		MajorVersion:       l.MajorVersion,
+		MinorVersion:       l.MinorVersion,
+		ApplicationVersion: l.ApplicationVersion,
+		Tracks:             make(map[string]Track, len(l.Tracks)),
+	}
+	if loc, ok := djLocation(l.MusicFolder); ok {
+		dj.MusicFolder = loc
+	}
+
+	for _, t := range l.sortedTracks() {
+		loc, ok := djLocation(t.Location)
+		if !ok {
+			continue
+		}
+		dj.Tracks[strconv.Itoa(t.TrackID)] = Track{
+			TrackID:   t.TrackID,
+			Name:      t.Name,
+			Artist:    t.Artist,
+			Album:     t.Album,
+			TotalTime: t.TotalTime,
+			BPM:       t.BPM,
+			Location:  loc,
+		}
+	}
+
+	dj.Playlists = make([]Playlist, 0, len(l.Playlists))
+	for _, p := range l.Playlists {
+		items := make([]PlaylistItem, 0, len(p.PlaylistItems))
+		for _, item := range p.PlaylistItems {
+			if _, ok := dj.Tracks[strconv.Itoa(item.TrackID)]; ok {
+				items = append(items, PlaylistItem{TrackID: item.TrackID})
+			}
+		}
+		dj.Playlists = append(dj.Playlists, Playlist{
+			Name:                 p.Name,
+			Master:               p.Master,
+			Visible:              p.Visible,
+			PlaylistID:           p.PlaylistID,
+			PlaylistPersistentID: p.PlaylistPersistentID,
+			ParentPersistentID:   p.ParentPersistentID,
+			Folder:               p.Folder,
+			PlaylistItems:        items,
+		})
+	}
+	return WriteToXML(w, dj)
+}
+
+// djLocation returns the file URL loc in the file://localhost/ form with standard
+// percent-encoding, and false if loc is not a file URL.
+func djLocation(loc string) (string, bool) {
+	u, err := url.Parse(loc)
+	if err != nil || u.Scheme != "file" {
+		return "", false
+	}
+	if u.Host == "" {
+		u.Host = "localhost"
+	}
+	u.RawPath = ""
+	return u.String(), true
+}
+
+// TracksByBPMRange returns the tracks whose BPM is between min and max inclusive, ordered
+// by BPM and then TrackID.  Tracks with no BPM (which haven't been analysed) are never
+// returned.  iTunes doesn't store the musical key of a track, but keys written into other
+// fields by DJ software (such as Comments or Grouping) can be matched by combining this with
+// FilterTracks and a predicate which parses them.
+func (l Library) TracksByBPMRange(min, max int) []Track {
+	tracks := l.FilterTracks(func(t Track) bool {
+		return t.BPM != 0 && t.BPM >= min && t.BPM <= max
+	})
+	sort.SliceStable(tracks, func(i, j int) bool {
+		return tracks[i].BPM < tracks[j].BPM
+	})
+	return tracks
+}
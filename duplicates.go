@@ -0,0 +1,103 @@
+// Copyright 2014, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package itl
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// DuplicateTolerance is the maximum difference in TotalTime between tracks which
+// Duplicates considers to be the same recording.
+const DuplicateTolerance = 2 * time.Second
+
+var (
+	featBracketRegexp = regexp.MustCompile(`(?i)[(\[]\s*(feat|ft|featuring)\b[^)\]]*[)\]]`)
+	featRegexp        = regexp.MustCompile(`(?i)\s(feat|ft|featuring)\b.*$`)
+)
+
+// Duplicates returns groups of tracks which are likely to be the same recording: those
+// with the same DuplicateKey and a TotalTime within DuplicateTolerance of each other.
+func (l Library) Duplicates() [][]Track {
+	return l.DuplicatesBy(DuplicateKey, DuplicateTolerance)
+}
+
+// DuplicatesBy returns groups of two or more tracks which have the same key and whose
+// TotalTime differs by at most tolerance from the next longest track in the group.  Tracks
+// in each group are ordered by TrackID, and groups are ordered by their first TrackID.
+func (l Library) DuplicatesBy(key func(Track) string, tolerance time.Duration) [][]Track {
+	byKey := make(map[string][]Track)
+	var keys []string
+	for _, t := range l.sortedTracks() {
+		k := key(t)
+		if _, ok := byKey[k]; !ok {
+			keys = append(keys, k)
+		}
+		byKey[k] = append(byKey[k], t)
+	}
+
+	var groups [][]Track
+	for _, k := range keys {
+		tracks := byKey[k]
+		if len(tracks) < 2 {
+			continue
+		}
+		sort.SliceStable(tracks, func(i, j int) bool {
+			return tracks[i].TotalTime < tracks[j].TotalTime
+		})
+		start := 0
+		for i := 1; i <= len(tracks); i++ {
+			if i < len(tracks) && tracks[i].Duration()-tracks[i-1].Duration() <= tolerance {
+				continue
+			}
+			if i-start >= 2 {
+				g := append([]Track(nil), tracks[start:i]...)
+				sort.Slice(g, func(i, j int) bool { return g[i].TrackID < g[j].TrackID })
+				groups = append(groups, g)
+			}
+			start = i
+		}
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i][0].TrackID < groups[j][0].TrackID
+	})
+	return groups
+}
+
+// DuplicateKey is the default key used by Duplicates: the normalized Artist, Name and Album
+// of the track.  Normalization lower-cases, removes featured artist annotations ("feat.",
+// "ft.", "featuring"), removes a leading "The " or trailing ", The" and strips punctuation,
+// so that "The Beatles" and "Beatles, The" are the same.
+func DuplicateKey(t Track) string {
+	return normalizeMetadata(t.Artist) + "\x00" + normalizeMetadata(t.Name) + "\x00" + normalizeMetadata(t.Album)
+}
+
+// normalizeMetadata normalizes s for DuplicateKey.
+func normalizeMetadata(s string) string {
+	s = featBracketRegexp.ReplaceAllString(s, "")
+	s = featRegexp.ReplaceAllString(s, "")
+	s = strings.ToLower(strings.TrimSpace(s))
+	s = strings.TrimSuffix(s, ", the")
+	s = strings.TrimPrefix(s, "the ")
+
+	var b strings.Builder
+	space := false
+	for _, r := range s {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			space = false
+			b.WriteRune(r)
+		case unicode.IsSpace(r):
+			space = true
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,136 @@
+// Copyright 2014, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package itl
+
+import (
+	"sort"
+	"strconv"
+)
+
+// RenumberTracks assigns sequential TrackIDs, starting at 1, to the tracks in the library
+// (in order of their existing TrackIDs), rekeys the Tracks map and rewrites all references
+// in PlaylistItems and Genius Track IDs.  The mapping is built before anything is changed.
+// Playlist items which reference tracks not in the library are removed, since they can't
+// be mapped and their old IDs may now belong to other tracks.  If more than one track has the
+// same TrackID (see KeyMismatches), each is given its own new ID (in order of their keys in
+// Tracks) and references are mapped to the first, the one whose key sorts first.
+func (l *Library) RenumberTracks() {
+	tracks := l.sortedTracks()
+	ids := make(map[int]int, len(tracks))
+	for i, t := range tracks {
+		if _, ok := ids[t.TrackID]; !ok {
+			ids[t.TrackID] = i + 1
+		}
+	}
+
+	l.Tracks = make(map[string]Track, len(tracks))
+	for i, t := range tracks {
+		t.TrackID = i + 1
+		l.Tracks[strconv.Itoa(t.TrackID)] = t
+	}
+
+	for i := range l.Playlists {
+		p := &l.Playlists[i]
+		items := make([]PlaylistItem, 0, len(p.PlaylistItems))
+		for _, item := range p.PlaylistItems {
+			if id, ok := ids[item.TrackID]; ok {
+				item.TrackID = id
+				items = append(items, item)
+			}
+		}
+		p.PlaylistItems = items
+		if p.GeniusTrackID != 0 {
+			p.GeniusTrackID = ids[p.GeniusTrackID]
+		}
+	}
+}
+
+// UpsertTrack adds t to the library, replacing any track with the same TrackID.  The track
+// is stored under the key for its TrackID, and any other entries in the Tracks map holding a
+// track with the same TrackID (see KeyMismatches) are removed.
+func (l *Library) UpsertTrack(t Track) {
+	if l.Tracks == nil {
+		l.Tracks = make(map[string]Track)
+	}
+	k := strconv.Itoa(t.TrackID)
+	for ok, ot := range l.Tracks {
+		if ot.TrackID == t.TrackID && ok != k {
+			delete(l.Tracks, ok)
+		}
+	}
+	l.Tracks[k] = t
+}
+
+// RemoveTrack removes the track with the given TrackID from the library, along with every
+// PlaylistItem which references it, so that no playlist is left with dangling references.
+// Genius playlists seeded from the track have their GeniusTrackID cleared.  Every entry in
+// the Tracks map holding a track with the ID is removed, whatever its key.
+func (l *Library) RemoveTrack(id int) {
+	for k, t := range l.Tracks {
+		if t.TrackID == id {
+			delete(l.Tracks, k)
+		}
+	}
+
+	for i := range l.Playlists {
+		p := &l.Playlists[i]
+		items := make([]PlaylistItem, 0, len(p.PlaylistItems))
+		for _, item := range p.PlaylistItems {
+			if item.TrackID != id {
+				items = append(items, item)
+			}
+		}
+		if len(items) != len(p.PlaylistItems) {
+			p.PlaylistItems = items
+		}
+		if p.GeniusTrackID == id {
+			p.GeniusTrackID = 0
+		}
+	}
+}
+
+// UpdateWhere calls mutate for each track for which pred returns true, in ascending TrackID
+// order, and stores the modified track back in the library.  Every track is passed to pred
+// (and mutate) at most once, with the value it had before UpdateWhere was called.  If mutate
+// changes the TrackID then the track is moved to the key for its new ID, replacing any track
+// already there, but playlist items are not updated: use RenumberTracks to change IDs
+// consistently.
+func (l *Library) UpdateWhere(pred func(Track) bool, mutate func(*Track)) {
+	type entry struct {
+		k string
+		t Track
+	}
+	entries := make([]entry, 0, len(l.Tracks))
+	for k, t := range l.Tracks {
+		entries = append(entries, entry{k, t})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.t.TrackID != b.t.TrackID {
+			return a.t.TrackID < b.t.TrackID
+		}
+		return a.k < b.k
+	})
+
+	tracks := make(map[string]Track, len(entries))
+	var moved []Track
+	for _, e := range entries {
+		if !pred(e.t) {
+			tracks[e.k] = e.t
+			continue
+		}
+		id := e.t.TrackID
+		mutate(&e.t)
+		if e.t.TrackID != id {
+			moved = append(moved, e.t)
+			continue
+		}
+		tracks[e.k] = e.t
+	}
+	for _, t := range moved {
+		tracks[strconv.Itoa(t.TrackID)] = t
+	}
+	l.Tracks = tracks
+}
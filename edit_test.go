@@ -0,0 +1,74 @@
+// Copyright 2014, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package itl
+
+import "testing"
+
+func TestUpdateWhereChangedTrackID(t *testing.T) {
+	l := Library{Tracks: map[string]Track{
+		"1": {TrackID: 1, Name: "a"},
+		"2": {TrackID: 2, Name: "b"},
+	}}
+	calls := 0
+	l.UpdateWhere(func(Track) bool { return true }, func(t *Track) {
+		calls++
+		if t.TrackID == 1 {
+			t.TrackID = 2
+		}
+		t.Name += "!"
+	})
+
+	if calls != 2 {
+		t.Errorf("mutate calls = %d, want 2", calls)
+	}
+	if len(l.Tracks) != 1 {
+		t.Fatalf("len(Tracks) = %d, want 1: %v", len(l.Tracks), l.Tracks)
+	}
+	if got := l.Tracks["2"]; got.TrackID != 2 || got.Name != "a!" {
+		t.Errorf("Tracks[\"2\"] = %d %q, want 2 \"a!\"", got.TrackID, got.Name)
+	}
+}
+
+func TestUpdateWhere(t *testing.T) {
+	l := Library{Tracks: map[string]Track{
+		"1": {TrackID: 1, Composer: "Bach"},
+		"2": {TrackID: 2, Composer: "Bach"},
+		"3": {TrackID: 3, Composer: "Miles Davis"},
+	}}
+	l.UpdateWhere(func(t Track) bool { return t.Composer == "Bach" }, func(t *Track) {
+		t.Genre = "Classical"
+	})
+
+	want := map[string]string{"1": "Classical", "2": "Classical", "3": ""}
+	for k, g := range want {
+		if got := l.Tracks[k].Genre; got != g {
+			t.Errorf("Tracks[%q].Genre = %q, want %q", k, got, g)
+		}
+	}
+}
+
+func TestRenumberTracksSharedTrackID(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		l := Library{
+			Tracks: map[string]Track{
+				"7": {TrackID: 5, Name: "seven"},
+				"5": {TrackID: 5, Name: "five"},
+				"3": {TrackID: 3, Name: "three"},
+			},
+			Playlists: []Playlist{{PlaylistItems: []PlaylistItem{{TrackID: 5}, {TrackID: 3}}}},
+		}
+		l.RenumberTracks()
+
+		want := map[string]string{"1": "three", "2": "five", "3": "seven"}
+		for k, name := range want {
+			if got := l.Tracks[k].Name; got != name {
+				t.Fatalf("Tracks[%q].Name = %q, want %q", k, got, name)
+			}
+		}
+		if got := l.Playlists[0].PlaylistItems; len(got) != 2 || got[0].TrackID != 2 || got[1].TrackID != 1 {
+			t.Fatalf("PlaylistItems = %v, want [{2} {1}]", got)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+// Copyright 2014, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package itl
+
+// ParseError is the error returned by ReadFromXML, ReadFromBytes and ReadFromXMLContext
+// when the library can't be read.  Op distinguishes failures reading the input from
+// failures parsing it.
+type ParseError struct {
+	// Op is "read" if reading from the io.Reader failed, or "unmarshal" if the data
+	// could not be parsed as an iTunes library plist.
+	Op  string
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	return "itl: " + e.Op + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,104 @@
+// Copyright 2014, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package itl
+
+import (
+	"io"
+	"strconv"
+)
+
+// ExportPlaylist writes a new library to w, in the same format as WriteToXML, containing only
+// the playlist p and the tracks it references.  Tracks are given new TrackIDs, numbered from 1
+// in playlist order, and the playlist items are rewritten to match.  Items referencing tracks
+// which are missing from l are dropped.  The playlist's GeniusTrackID is rewritten in the same
+// way, or cleared if that track isn't in the playlist.  The playlist's ParentPersistentID is
+// cleared as its parent is not exported.  The top-level library values are copied from l.
+func (l Library) ExportPlaylist(w io.Writer, p Playlist) error {
+	e := l
+	e.Tracks = make(map[string]Track)
+
+	ids := make(map[int]int)
+	items := make([]PlaylistItem, 0, len(p.PlaylistItems))
+	for _, item := range p.PlaylistItems {
+		t, ok := l.GetTrack(item.TrackID)
+		if !ok {
+			continue
+		}
+		id, ok := ids[item.TrackID]
+		if !ok {
+			id = len(ids) + 1
+			ids[item.TrackID] = id
+			t.TrackID = id
+			e.Tracks[strconv.Itoa(id)] = t
+		}
+		item.TrackID = id
+		items = append(items, item)
+	}
+
+	p.PlaylistItems = items
+	p.GeniusTrackID = ids[p.GeniusTrackID]
+	p.ParentPersistentID = ""
+	e.Playlists = []Playlist{p}
+	return WriteToXML(w, e)
+}
+
+// Subset returns a new library containing only the tracks for which pred returns true, with
+// every playlist's items rewritten to drop those which reference other (or missing) tracks,
+// so that the result is self-contained and can be written with WriteToXML.  Tracks keep their
+// TrackIDs, playlists are all kept (even if they become empty, see PruneEmptyPlaylists) and
+// the top-level library values are copied from l.  The result shares no data with l.
+func (l Library) Subset(pred func(Track) bool) Library {
+	s := l
+	s.Extra = cloneExtra(l.Extra)
+	s.Tracks = make(map[string]Track)
+	for _, t := range l.FilterTracks(pred) {
+		s.Tracks[strconv.Itoa(t.TrackID)] = t.clone()
+	}
+
+	s.Playlists = make([]Playlist, len(l.Playlists))
+	for i, p := range l.Playlists {
+		p = p.clone()
+		items := p.PlaylistItems[:0]
+		for _, item := range p.PlaylistItems {
+			if _, ok := s.Tracks[strconv.Itoa(item.TrackID)]; ok {
+				items = append(items, item)
+			}
+		}
+		p.PlaylistItems = items
+		if _, ok := s.Tracks[strconv.Itoa(p.GeniusTrackID)]; !ok {
+			p.GeniusTrackID = 0
+		}
+		s.Playlists[i] = p
+	}
+	return s
+}
+
+// PruneEmptyPlaylists removes the user created playlists (see Playlist.IsUserCreated) which
+// have no items, and then the folders which are left with no playlists in them (repeatedly,
+// so nested empty folders are removed).  The master and built-in playlists are never removed.
+func (l *Library) PruneEmptyPlaylists() {
+	for {
+		children := make(map[string]int)
+		for _, p := range l.Playlists {
+			if p.ParentPersistentID != "" {
+				children[p.ParentPersistentID]++
+			}
+		}
+		kept := make([]Playlist, 0, len(l.Playlists))
+		for _, p := range l.Playlists {
+			empty := len(p.PlaylistItems) == 0
+			if p.IsFolder() {
+				empty = children[p.PlaylistPersistentID] == 0
+			}
+			if !p.IsUserCreated() || !empty {
+				kept = append(kept, p)
+			}
+		}
+		if len(kept) == len(l.Playlists) {
+			return
+		}
+		l.Playlists = kept
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2014, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package itl
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestExportPlaylist(t *testing.T) {
+	l := Library{
+		Tracks: map[string]Track{
+			"10": {TrackID: 10, Name: "Ten"},
+			"20": {TrackID: 20, Name: "Twenty"},
+			"30": {TrackID: 30, Name: "Thirty"},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		playlist  Playlist
+		wantItems []int
+		wantNames []string
+		genius    int
+	}{
+		{
+			name: "genius track in playlist",
+			playlist: Playlist{
+				Name:               "Genius",
+				GeniusTrackID:      20,
+				ParentPersistentID: "ABCD",
+				PlaylistItems:      []PlaylistItem{{TrackID: 30}, {TrackID: 99}, {TrackID: 20}, {TrackID: 30}},
+			},
+			wantItems: []int{1, 2, 1},
+			wantNames: []string{"Thirty", "Twenty"},
+			genius:    2,
+		},
+		{
+			name: "genius track not in playlist",
+			playlist: Playlist{
+				Name:          "Genius",
+				GeniusTrackID: 10,
+				PlaylistItems: []PlaylistItem{{TrackID: 30}},
+			},
+			wantItems: []int{1},
+			wantNames: []string{"Thirty"},
+			genius:    0,
+		},
+	}
+
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := l.ExportPlaylist(&buf, tt.playlist); err != nil {
+			t.Fatalf("%s: ExportPlaylist() error = %v", tt.name, err)
+		}
+		got, err := Decode(&buf)
+		if err != nil {
+			t.Fatalf("%s: Decode() error = %v", tt.name, err)
+		}
+
+		if len(got.Playlists) != 1 {
+			t.Fatalf("%s: len(Playlists) = %d, want 1", tt.name, len(got.Playlists))
+		}
+		p := got.Playlists[0]
+		if p.GeniusTrackID != tt.genius {
+			t.Errorf("%s: GeniusTrackID = %d, want %d", tt.name, p.GeniusTrackID, tt.genius)
+		}
+		if p.ParentPersistentID != "" {
+			t.Errorf("%s: ParentPersistentID = %q, want \"\"", tt.name, p.ParentPersistentID)
+		}
+
+		var items []int
+		for _, item := range p.PlaylistItems {
+			items = append(items, item.TrackID)
+		}
+		if !reflect.DeepEqual(items, tt.wantItems) {
+			t.Errorf("%s: PlaylistItems = %v, want %v", tt.name, items, tt.wantItems)
+		}
+
+		if len(got.Tracks) != len(tt.wantNames) {
+			t.Errorf("%s: len(Tracks) = %d, want %d", tt.name, len(got.Tracks), len(tt.wantNames))
+		}
+		for i, name := range tt.wantNames {
+			if tr, ok := got.GetTrack(i + 1); !ok || tr.Name != name {
+				t.Errorf("%s: GetTrack(%d) = %q, %v, want %q, true", tt.name, i+1, tr.Name, ok, name)
+			}
+		}
+	}
+}
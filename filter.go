@@ -0,0 +1,138 @@
+// Copyright 2014, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package itl
+
+import (
+	"sort"
+	"time"
+)
+
+// TracksAddedSince returns the tracks added to the library at or after t, most recently
+// added first.  Tracks without a DateAdded are never included.
+func (l Library) TracksAddedSince(t time.Time) []Track {
+	return l.tracksSince(t, func(t Track) time.Time { return t.DateAdded })
+}
+
+// TracksPlayedSince returns the tracks last played at or after t (using PlayDateUTC), most
+// recently played first.  Tracks without a PlayDateUTC are never included.
+func (l Library) TracksPlayedSince(t time.Time) []Track {
+	return l.tracksSince(t, func(t Track) time.Time { return t.PlayDateUTC })
+}
+
+// TracksInImportOrder returns all the tracks in the order they were added to the library:
+// by DateAdded, with tracks added at the same time ordered by TrackID (which iTunes assigns
+// in increasing order).  Tracks without a DateAdded are at the end, in TrackID order.
+func (l Library) TracksInImportOrder() []Track {
+	tracks := l.sortedTracks()
+	sort.SliceStable(tracks, func(i, j int) bool {
+		a, b := tracks[i].DateAdded, tracks[j].DateAdded
+		if a.IsZero() || b.IsZero() {
+			return !a.IsZero() && b.IsZero()
+		}
+		return a.Before(b)
+	})
+	return tracks
+}
+
+// tracksSince returns the tracks whose date is non-zero and not before since, ordered by
+// date descending and then by TrackID.
+func (l Library) tracksSince(since time.Time, date func(Track) time.Time) []Track {
+	tracks := l.FilterTracks(func(t Track) bool {
+		d := date(t)
+		return !d.IsZero() && !d.Before(since)
+	})
+	sort.SliceStable(tracks, func(i, j int) bool {
+		return date(tracks[i]).After(date(tracks[j]))
+	})
+	return tracks
+}
+
+// FilterTracks returns the tracks in the library for which pred returns true, in
+// ascending TrackID order.
+func (l Library) FilterTracks(pred func(Track) bool) []Track {
+	var tracks []Track
+	for _, t := range l.sortedTracks() {
+		if pred(t) {
+			tracks = append(tracks, t)
+		}
+	}
+	return tracks
+}
+
+// Loved is a predicate for FilterTracks which matches loved tracks.
+func Loved(t Track) bool {
+	return t.Loved
+}
+
+// Unplayed is a predicate for FilterTracks which matches tracks which have never
+// been played.
+func Unplayed(t Track) bool {
+	return t.PlayCount == 0
+}
+
+// RatingAtLeast returns a predicate for FilterTracks which matches tracks with a rating
+// of at least n stars.  iTunes stores ratings in the range 0-100, with 20 per star.
+func RatingAtLeast(n int) func(Track) bool {
+	return func(t Track) bool {
+		return t.Rating >= n*20
+	}
+}
+
+// NeverPlayed is a predicate for FilterTracks which matches tracks with a PlayCount of
+// zero.  It is the same as Unplayed.
+func NeverPlayed(t Track) bool {
+	return Unplayed(t)
+}
+
+// Purchased is a predicate for FilterTracks which matches purchased tracks.
+func Purchased(t Track) bool {
+	return t.Purchased
+}
+
+// Protected is a predicate for FilterTracks which matches protected (DRM) tracks.
+func Protected(t Track) bool {
+	return t.Protected
+}
+
+// HasArtwork is a predicate for FilterTracks which matches tracks with artwork.
+func HasArtwork(t Track) bool {
+	return t.HasArtwork()
+}
+
+// IsVideo is a predicate for FilterTracks which matches tracks with video.
+func IsVideo(t Track) bool {
+	return t.HasVideo
+}
+
+// And returns a predicate which matches tracks matched by all of preds.
+func And(preds ...func(Track) bool) func(Track) bool {
+	return func(t Track) bool {
+		for _, p := range preds {
+			if !p(t) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Or returns a predicate which matches tracks matched by any of preds.
+func Or(preds ...func(Track) bool) func(Track) bool {
+	return func(t Track) bool {
+		for _, p := range preds {
+			if p(t) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Not returns a predicate which matches tracks not matched by pred.
+func Not(pred func(Track) bool) func(Track) bool {
+	return func(t Track) bool {
+		return !pred(t)
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2014, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package itl
+
+import (
+	"strings"
+	"unicode"
+)
+
+// GenreAliases maps genre keys to canonical genre names, and is used by NormalizeGenre.  The
+// key of a genre is its lower-cased letters and digits, so "Hip-Hop", "hip hop" and "HipHop"
+// all have the key "hiphop", and "R&B" the key "rb".  It can be modified to add or change
+// mappings, but must not be modified while NormalizeGenre may be called.
+var GenreAliases = map[string]string{
+	"hiphop":          "Hip-Hop/Rap",
+	"hiphoprap":       "Hip-Hop/Rap",
+	"rap":             "Hip-Hop/Rap",
+	"rb":              "R&B/Soul",
+	"rnb":             "R&B/Soul",
+	"randb":           "R&B/Soul",
+	"rbsoul":          "R&B/Soul",
+	"rhythmandblues":  "R&B/Soul",
+	"soul":            "R&B/Soul",
+	"electronic":      "Electronic",
+	"electronica":     "Electronic",
+	"electro":         "Electronic",
+	"dance":           "Dance",
+	"edm":             "Dance",
+	"alternative":     "Alternative",
+	"alt":             "Alternative",
+	"alternativerock": "Alternative",
+	"indie":           "Alternative",
+	"indierock":       "Alternative",
+	"rock":            "Rock",
+	"rocknroll":       "Rock",
+	"rockandroll":     "Rock",
+	"pop":             "Pop",
+	"metal":           "Metal",
+	"heavymetal":      "Metal",
+	"jazz":            "Jazz",
+	"blues":           "Blues",
+	"classical":       "Classical",
+	"country":         "Country",
+	"folk":            "Folk",
+	"reggae":          "Reggae",
+	"latin":           "Latin",
+	"world":           "World",
+	"worldmusic":      "World",
+	"soundtrack":      "Soundtrack",
+	"soundtracks":     "Soundtrack",
+	"ost":             "Soundtrack",
+	"filmscore":       "Soundtrack",
+	"spokenword":      "Spoken Word",
+}
+
+// NormalizeGenre returns the canonical name of the genre g from GenreAliases, or g with
+// surrounding space trimmed if it has no alias.
+func NormalizeGenre(g string) string {
+	if c, ok := GenreAliases[genreKey(g)]; ok {
+		return c
+	}
+	return strings.TrimSpace(g)
+}
+
+// genreKey returns the key of the genre g in GenreAliases.
+func genreKey(g string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(g) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// NormalizedGenreDistribution returns the number of tracks in each genre, after normalizing
+// their Genre with NormalizeGenre.  Tracks without a genre are counted under "".
+func (l Library) NormalizedGenreDistribution() map[string]int {
+	m := make(map[string]int)
+	for _, t := range l.Tracks {
+		m[NormalizeGenre(t.Genre)]++
+	}
+	return m
+}
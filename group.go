@@ -0,0 +1,120 @@
+// Copyright 2014, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package itl
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// TracksByGenre returns the tracks in the library grouped by Genre.  Tracks without a
+// genre are grouped under the empty string.  Each group is in ascending TrackID order.
+func (l Library) TracksByGenre() map[string][]Track {
+	return l.groupTracks(func(t Track) string { return t.Genre })
+}
+
+// TracksByArtist returns the tracks in the library grouped by Artist.  Tracks without an
+// artist are grouped under the empty string.  Each group is in ascending TrackID order.
+func (l Library) TracksByArtist() map[string][]Track {
+	return l.groupTracks(func(t Track) string { return t.Artist })
+}
+
+// groupTracks returns the tracks in the library grouped by key, with each group in
+// ascending TrackID order.
+func (l Library) groupTracks(key func(Track) string) map[string][]Track {
+	m := make(map[string][]Track)
+	for _, t := range l.sortedTracks() {
+		k := key(t)
+		m[k] = append(m[k], t)
+	}
+	return m
+}
+
+// DistinctGenres returns the distinct Genres of the tracks in the library, see Distinct.
+func (l Library) DistinctGenres() []string {
+	return l.distinct(func(t Track) string { return t.Genre })
+}
+
+// DistinctArtists returns the distinct Artists of the tracks in the library, see Distinct.
+func (l Library) DistinctArtists() []string {
+	return l.distinct(func(t Track) string { return t.Artist })
+}
+
+// DistinctAlbumArtists returns the distinct AlbumArtists of the tracks in the library, see
+// Distinct.
+func (l Library) DistinctAlbumArtists() []string {
+	return l.distinct(func(t Track) string { return t.AlbumArtist })
+}
+
+// Distinct returns the distinct values of the named Track field (either the Go field name,
+// such as "Composer", or the plist key, such as "Album Artist") of the tracks in the library.
+// Values have surrounding space trimmed, and empty values are omitted.  The result is sorted:
+// lexically for string fields, and numerically for integer fields (such as "Year"), whose
+// values are given in decimal with zero omitted.  Returns an error if there is no such field,
+// or it is not a string or integer field.
+func (l Library) Distinct(field string) ([]string, error) {
+	f, ok := trackField(field)
+	if !ok {
+		return nil, fmt.Errorf("itl: no track field %q", field)
+	}
+
+	switch f.Type.Kind() {
+	case reflect.String:
+		return l.distinct(func(t Track) string {
+			return reflect.ValueOf(t).FieldByIndex(f.Index).String()
+		}), nil
+
+	case reflect.Int:
+		seen := make(map[int64]bool)
+		var ns []int64
+		for _, t := range l.Tracks {
+			n := reflect.ValueOf(t).FieldByIndex(f.Index).Int()
+			if n != 0 && !seen[n] {
+				seen[n] = true
+				ns = append(ns, n)
+			}
+		}
+		sort.Slice(ns, func(i, j int) bool { return ns[i] < ns[j] })
+		vs := make([]string, len(ns))
+		for i, n := range ns {
+			vs[i] = strconv.FormatInt(n, 10)
+		}
+		return vs, nil
+	}
+	return nil, fmt.Errorf("itl: track field %q is not a string or integer", field)
+}
+
+// trackField returns the Track field with the given Go field name or plist key, and true
+// if there is one.
+func trackField(name string) (reflect.StructField, bool) {
+	if f, ok := trackType.FieldByName(name); ok {
+		return f, true
+	}
+	for i := 0; i < trackType.NumField(); i++ {
+		if f := trackType.Field(i); fieldKey(f) == name {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// distinct returns the sorted, distinct, non-empty values of value for the tracks in the
+// library, with surrounding space trimmed.
+func (l Library) distinct(value func(Track) string) []string {
+	seen := make(map[string]bool)
+	var vs []string
+	for _, t := range l.Tracks {
+		v := strings.TrimSpace(value(t))
+		if v != "" && !seen[v] {
+			seen[v] = true
+			vs = append(vs, v)
+		}
+	}
+	sort.Strings(vs)
+	return vs
+}
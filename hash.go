@@ -0,0 +1,64 @@
+// Copyright 2014, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package itl
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// volatileFields are the Track fields which iTunes changes when a track is played or
+// skipped, and which ContentHash can exclude.
+var volatileFields = map[string]bool{
+	"PlayCount":   true,
+	"PlayDate":    true,
+	"PlayDateUTC": true,
+	"SkipCount":   true,
+	"SkipDate":    true,
+	"Unplayed":    true,
+}
+
+// Equal returns true if t and other have the same value for every field.  Times are
+// compared with time.Time.Equal, so the same instant in different locations is equal.
+func (t Track) Equal(other Track) bool {
+	tv, ov := reflect.ValueOf(t), reflect.ValueOf(other)
+	for i := 0; i < trackType.NumField(); i++ {
+		if !valuesEqual(tv.Field(i).Interface(), ov.Field(i).Interface()) {
+			return false
+		}
+	}
+	return true
+}
+
+// ContentHash returns a hex-encoded SHA-256 hash of the metadata of the track, which can
+// be stored and compared later to detect changes.  The hash covers every field of Track
+// except TrackID (which isn't stable, see RenumberTracks) and Extra.  If excludeVolatile
+// is true, the fields which change whenever the track is played or skipped (PlayCount,
+// PlayDate, PlayDateUTC, SkipCount, SkipDate and Unplayed) are also excluded.  Fields
+// with zero values don't contribute to the hash, so it is unaffected by new fields being
+// added to Track.  Times are hashed as UTC instants.
+func (t Track) ContentHash(excludeVolatile bool) string {
+	h := sha256.New()
+	v := reflect.ValueOf(t)
+	for i := 0; i < trackType.NumField(); i++ {
+		f := trackType.Field(i)
+		fv := v.Field(i)
+		if f.Name == "TrackID" || f.Name == "Extra" || isEmptyValue(fv) {
+			continue
+		}
+		if excludeVolatile && volatileFields[f.Name] {
+			continue
+		}
+		x := fv.Interface()
+		if tm, ok := x.(time.Time); ok {
+			x = tm.UTC().Format(time.RFC3339)
+		}
+		fmt.Fprintf(h, "%s\x00%v\x00", f.Name, x)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,69 @@
+// Copyright 2014, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package itl
+
+import (
+	"os"
+	"sync"
+)
+
+// DeadTracks returns the tracks whose Location is a local file which no longer exists, in
+// ascending TrackID order.  Tracks without a local file Location (such as remote streams)
+// are skipped.  The whole library is always checked: if any file can't be checked for a
+// reason other than not existing (for instance a permission error) then the first such
+// error is returned along with the dead tracks found.
+func (l Library) DeadTracks() ([]Track, error) {
+	var dead []Track
+	var firstErr error
+	for _, t := range l.sortedTracks() {
+		path, err := t.LocalPath()
+		if err != nil || path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			if os.IsNotExist(err) {
+				dead = append(dead, t)
+			} else if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return dead, firstErr
+}
+
+// ResolvePaths returns the local path (see LocalPath) of every track which has one, keyed
+// by TrackID.  Locations are decoded in parallel by a pool of concurrency goroutines (at
+// least one).  No files are opened or checked: use DeadTracks to find paths which no longer
+// exist.  Tracks which aren't local files are omitted.
+func (l Library) ResolvePaths(concurrency int) map[int]string {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	tracks := make(chan Track)
+	paths := make(map[int]string, len(l.Tracks))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for t := range tracks {
+				path, err := t.LocalPath()
+				if err != nil || path == "" {
+					continue
+				}
+				mu.Lock()
+				paths[t.TrackID] = path
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, t := range l.Tracks {
+		tracks <- t
+	}
+	close(tracks)
+	wg.Wait()
+	return paths
+}
@@ -0,0 +1,52 @@
+// Copyright 2014, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package itl
+
+// Index provides fast lookups of the tracks in a Library, see Library.BuildIndex.  An
+// Index is not updated when the Library changes.
+type Index struct {
+	byTrackID      map[int]Track
+	byPersistentID map[string]Track
+	byLocation     map[string]Track
+}
+
+// BuildIndex returns an Index of the tracks in the library.  If more than one track has
+// the same PersistentID or Location then the one with the lowest TrackID is indexed.
+func (l Library) BuildIndex() *Index {
+	x := &Index{
+		byTrackID:      make(map[int]Track, len(l.Tracks)),
+		byPersistentID: make(map[string]Track, len(l.Tracks)),
+		byLocation:     make(map[string]Track, len(l.Tracks)),
+	}
+	for _, t := range l.sortedTracks() {
+		x.byTrackID[t.TrackID] = t
+		if _, ok := x.byPersistentID[t.PersistentID]; !ok && t.PersistentID != "" {
+			x.byPersistentID[t.PersistentID] = t
+		}
+		if _, ok := x.byLocation[t.Location]; !ok && t.Location != "" {
+			x.byLocation[t.Location] = t
+		}
+	}
+	return x
+}
+
+// ByTrackID returns the track with the given TrackID, and true if it was found.
+func (x *Index) ByTrackID(id int) (Track, bool) {
+	t, ok := x.byTrackID[id]
+	return t, ok
+}
+
+// ByPersistentID returns the track with the given PersistentID, and true if it was found.
+func (x *Index) ByPersistentID(id string) (Track, bool) {
+	t, ok := x.byPersistentID[id]
+	return t, ok
+}
+
+// ByLocation returns the track with the given Location (a file URL, as stored in the
+// library), and true if it was found.
+func (x *Index) ByLocation(location string) (Track, bool) {
+	t, ok := x.byLocation[location]
+	return t, ok
+}
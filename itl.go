@@ -6,26 +6,34 @@
 package itl
 
 import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
 	"io"
 	"io/ioutil"
+	"os"
 	"time"
-
-	"github.com/dhowden/plist"
 )
 
 // Library represents the root iTunes library entity which includes a map of tracks and slice of
 // playlists.
 type Library struct {
-	MajorVersion        int `plist:"Major Version"`
-	MinorVersion        int `plist:"Minor Version"`
-	Date                time.Time
-	ApplicationVersion  string `plist:"Application Version"`
+	MajorVersion        int       `plist:"Major Version"`
+	MinorVersion        int       `plist:"Minor Version"`
+	Date                time.Time `plist:"Date"`
+	ApplicationVersion  string    `plist:"Application Version"`
 	Features            int
 	ShowContentRatings  bool   `plist:"Show Content Ratings"`
 	MusicFolder         string `plist:"Music Folder"`
 	LibraryPersistentID string `plist:"Library Persistent ID"`
 	Tracks              map[string]Track
 	Playlists           []Playlist
+
+	// Extra holds values for any keys which don't correspond to a field.  It is filled
+	// by all the Read and Decode functions, and written by WriteToXML, so keys unknown to
+	// this package are preserved when a library is read and written back.
+	Extra map[string]interface{} `plist:"-"`
 }
 
 // Track represents an iTunes library track, which is a media file which can either be music or video.
@@ -67,24 +75,32 @@ type Track struct {
 	SortAlbumArtist string `plist:"Sort Album Artist"`
 	SortAlbum       string `plist:"Sort Album"`
 	SortComposer    string `plist:"Sort Composer"`
+	SortSeries      string `plist:"Sort Series"`
 
 	Clean  bool
 	Series string
 
 	TotalTime        int       `plist:"Total Time"`
+	StartTime        int       `plist:"Start Time"`
+	StopTime         int       `plist:"Stop Time"`
 	DateModified     time.Time `plist:"Date Modified"`
 	DateAdded        time.Time `plist:"Date Added"`
+	Date             time.Time `plist:"Date"`
 	BitRate          int       `plist:"Bit Rate"`
 	SampleRate       int       `plist:"Sample Rate"`
 	VolumeAdjustment int       `plist:"Volume Adjustment"`
+	Equalizer        string    `plist:"Equalizer"`
 	Comments         string
 
 	PlayCount   int       `plist:"Play Count"`
 	PlayDate    int       `plist:"Play Date"`
 	PlayDateUTC time.Time `plist:"Play Date UTC"`
 
-	Protected bool
-	Purchased bool
+	Protected    bool
+	Purchased    bool
+	AppleMusic   bool `plist:"Apple Music"`
+	Matched      bool
+	PlaylistOnly bool `plist:"Playlist Only"`
 
 	SkipCount int       `plist:"Skip Count"`
 	SkipDate  time.Time `plist:"Skip Date"`
@@ -114,19 +130,35 @@ type Track struct {
 	Compilation bool
 	ReleaseDate time.Time `plist:"Release Date"`
 
+	Work           string
+	MovementName   string `plist:"Movement Name"`
+	MovementNumber int    `plist:"Movement Number"`
+	MovementCount  int    `plist:"Movement Count"`
+
+	// Bookmark is the saved playback position in milliseconds of a Bookmarkable track
+	// (such as an audiobook), from which playback resumes.
+	Bookmarkable bool
+	Bookmark     int
+
 	FileFolderCount    int `plist:"File Folder Count"`
 	LibraryFolderCount int `plist:"Library Folder Count"`
+
+	// Extra holds values for any keys which don't correspond to a field, see Library.Extra.
+	Extra map[string]interface{} `plist:"-"`
 }
 
-// Playlist represents an iTunes playlist.
+// Playlist represents an iTunes playlist.  Visible is false for playlists which iTunes
+// hides, such as the master playlist: iTunes only writes the Visible key for these, so it is
+// true when the key is missing, and should be set for new playlists.
 type Playlist struct {
 	Name                 string
+	Description          string
 	Master               bool
 	PlaylistID           int    `plist:"Playlist ID"`
 	ParentPersistentID   string `plist:"Parent Persistent ID"`
 	PlaylistPersistentID string `plist:"Playlist Persistent ID"`
 	DistinguishedKind    int    `plist:"Distinguished Kind"`
-	Visible              bool
+	Visible              bool   `plist:"Visible,keepfalse"`
 	Music                bool
 	Movies               bool
 	TVShows              bool `plist:"TV Shows"`
@@ -135,21 +167,119 @@ type Playlist struct {
 	Audiobooks           bool
 	AllItems             bool `plist:"All Items"`
 	Folder               bool
+	GeniusTrackID        int            `plist:"Genius Track ID"`
+	SmartInfo            []byte         `plist:"Smart Info"`
+	SmartCriteria        []byte         `plist:"Smart Criteria"`
 	PlaylistItems        []PlaylistItem `plist:"Playlist Items"`
+
+	// Extra holds values for any keys which don't correspond to a field, see Library.Extra.
+	Extra map[string]interface{} `plist:"-"`
 }
 
 // PlaylistItem represents an individual track in a an iTunes playlist.
 type PlaylistItem struct {
-	TrackID int `plist:"Track ID"`
+	TrackID        int `plist:"Track ID"`
+	PlaylistItemID int `plist:"Playlist Item ID"`
 }
 
 // ReadFromXML reads iTunes XML (plist) data from the underlying io.Reader
-// returning the resuling Library.
+// returning the resuling Library.  Errors are returned as a *ParseError.
 func ReadFromXML(r io.Reader) (l Library, err error) {
 	b, err := ioutil.ReadAll(r)
 	if err != nil {
+		err = &ParseError{Op: "read", Err: err}
 		return
 	}
-	err = plist.Unmarshal(b, &l)
+	return ReadFromBytes(b)
+}
+
+// ReadFromBytes reads iTunes XML (plist) data from b returning the resulting Library.
+// It decodes b directly, without the copy made by ReadFromXML.  Errors are returned as
+// a *ParseError.
+func ReadFromBytes(b []byte) (l Library, err error) {
+	if err = NewDecoder(bytes.NewReader(b)).Decode(&l); err != nil {
+		err = &ParseError{Op: "unmarshal", Err: err}
+	}
 	return
 }
+
+// readChunkSize is the size of the reads made by ReadFromXMLContext between checks
+// of its context.
+const readChunkSize = 64 << 10
+
+// ReadFromXMLContext is like ReadFromXML, but returns ctx.Err() as soon as possible once
+// ctx is done.  The context is checked between each chunk read from r, and before and after
+// the data is unmarshalled.  Unmarshalling itself cannot be interrupted: on cancellation it is
+// left to complete in the background and its result is discarded.  The returned Library is
+// unusable if a non-nil error is returned.  Errors are returned as a *ParseError, unless
+// they come from ctx.
+func ReadFromXMLContext(ctx context.Context, r io.Reader) (Library, error) {
+	var buf bytes.Buffer
+	chunk := make([]byte, readChunkSize)
+	for {
+		if err := ctx.Err(); err != nil {
+			return Library{}, err
+		}
+		n, err := r.Read(chunk)
+		buf.Write(chunk[:n])
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return Library{}, &ParseError{Op: "read", Err: err}
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return Library{}, err
+	}
+
+	type result struct {
+		l   Library
+		err error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		l, err := ReadFromBytes(buf.Bytes())
+		ch <- result{l, err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return Library{}, ctx.Err()
+	case res := <-ch:
+		return res.l, res.err
+	}
+}
+
+// ReadFromFile opens the named file and reads iTunes XML (plist) data from it,
+// returning the resulting Library.  Gzip-compressed files (such as Library.xml.gz
+// backups) are detected and decompressed.  The file is always closed before returning.
+// If the file cannot be opened the error is the one returned by os.Open.
+func ReadFromFile(path string) (Library, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Library{}, err
+	}
+	defer f.Close()
+
+	br := bufio.NewReader(f)
+	if magic, err := br.Peek(2); err == nil && magic[0] == gzipMagic[0] && magic[1] == gzipMagic[1] {
+		return ReadFromGzip(br)
+	}
+	return ReadFromXML(br)
+}
+
+// gzipMagic is the header which begins gzip-compressed data.
+var gzipMagic = [2]byte{0x1f, 0x8b}
+
+// ReadFromGzip reads gzip-compressed iTunes XML (plist) data from the underlying io.Reader
+// returning the resulting Library.
+func ReadFromGzip(r io.Reader) (Library, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return Library{}, err
+	}
+	defer zr.Close()
+	return ReadFromXML(zr)
+}
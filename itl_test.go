@@ -0,0 +1,82 @@
+// Copyright 2014, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package itl
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadFromXMLExtra(t *testing.T) {
+	const in = `<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0">
+<dict>
+	<key>Major Version</key><integer>1</integer>
+	<key>Tracks</key>
+	<dict>
+		<key>1</key>
+		<dict>
+			<key>Track ID</key><integer>1</integer>
+			<key>Normalization</key><integer>1234</integer>
+		</dict>
+	</dict>
+	<key>Playlists</key>
+	<array>
+		<dict>
+			<key>Playlist ID</key><integer>2</integer>
+			<key>Smart Order</key><string>xyz</string>
+		</dict>
+	</array>
+	<key>Library Sort Key</key><string>abc</string>
+</dict>
+</plist>
+`
+	l, err := ReadFromXML(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("ReadFromXML() error = %v", err)
+	}
+	if got := l.Extra["Library Sort Key"]; got != "abc" {
+		t.Errorf("Library.Extra[\"Library Sort Key\"] = %#v, want \"abc\"", got)
+	}
+	if got := l.Tracks["1"].Extra["Normalization"]; got != int64(1234) {
+		t.Errorf("Track.Extra[\"Normalization\"] = %#v, want 1234", got)
+	}
+	if got := l.Playlists[0].Extra["Smart Order"]; got != "xyz" {
+		t.Errorf("Playlist.Extra[\"Smart Order\"] = %#v, want \"xyz\"", got)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteToXML(&buf, l); err != nil {
+		t.Fatalf("WriteToXML() error = %v", err)
+	}
+	for _, k := range []string{"Library Sort Key", "Normalization", "Smart Order"} {
+		if !strings.Contains(buf.String(), "<key>"+k+"</key>") {
+			t.Errorf("WriteToXML() output is missing key %q", k)
+		}
+	}
+}
+
+func TestReadFromXMLMatchesDecode(t *testing.T) {
+	want, err := Decode(strings.NewReader(testLibraryXML))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	got, err := ReadFromXML(strings.NewReader(testLibraryXML))
+	if err != nil {
+		t.Fatalf("ReadFromXML() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadFromXML() = %+v, want Decode() result %+v", got, want)
+	}
+}
+
+func TestReadFromBytesParseError(t *testing.T) {
+	_, err := ReadFromBytes([]byte("<plist><dict><key>Major Version</key><string>x</string></dict></plist>"))
+	if _, ok := err.(*ParseError); !ok {
+		t.Errorf("ReadFromBytes() error = %#v, want *ParseError", err)
+	}
+}
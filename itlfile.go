@@ -0,0 +1,236 @@
+// Copyright 2014, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package itl
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/aes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// ErrNotITL is returned by ReadFromITL when the data is not an iTunes Library.itl file.
+var ErrNotITL = errors.New("itl: not an iTunes Library.itl file")
+
+// itlKey is the AES-128 key used to encrypt the payload of .itl files.
+var itlKey = []byte("BHUILuilfghuila3")
+
+// itlMaxCryptOffset is the offset in the hdfm header (of iTunes 10 and later) of the number
+// of bytes of the payload which are encrypted.  Earlier versions encrypt all of it.
+const itlMaxCryptOffset = 0x5c
+
+// itlTrackStrings maps the hohm types of track strings to the Track field they set.
+var itlTrackStrings = map[uint32]func(*Track, string){
+	0x02: func(t *Track, s string) { t.Name = s },
+	0x03: func(t *Track, s string) { t.Album = s },
+	0x04: func(t *Track, s string) { t.Artist = s },
+	0x05: func(t *Track, s string) { t.Genre = s },
+	0x06: func(t *Track, s string) { t.Kind = s },
+	0x08: func(t *Track, s string) { t.Comments = s },
+	0x0b: func(t *Track, s string) { t.Location = s },
+	0x0c: func(t *Track, s string) { t.Composer = s },
+}
+
+// itlPlaylistName is the hohm type of a playlist name.
+const itlPlaylistName = 0x64
+
+// ReadFromITL reads the binary iTunes Library.itl file format from r, returning the
+// resulting Library.  The format is undocumented and changes between versions of iTunes,
+// so support is partial and best effort, following published reverse engineering of the
+// format:
+//
+//   - ApplicationVersion is set from the file header
+//   - tracks have their TrackID, Name, Album, Artist, Genre, Kind, Comments, Location and
+//     Composer set
+//   - playlists have their Name and PlaylistItems set; PlaylistIDs are assigned in order
+//
+// No other values are read.  Files from both big-endian (older) and little-endian (newer)
+// versions of iTunes are recognised.  Returns ErrNotITL if r doesn't begin with an .itl
+// header, and an error if the structure of the file isn't understood.
+func ReadFromITL(r io.Reader) (Library, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return Library{}, err
+	}
+	version, data, err := itlPayload(b)
+	if err != nil {
+		return Library{}, err
+	}
+	l, err := itlParse(data)
+	if err != nil {
+		return Library{}, err
+	}
+	l.ApplicationVersion = version
+	return l, nil
+}
+
+// itlPayload parses the hdfm header of the .itl file b, returning the application version
+// and the decrypted and decompressed payload.
+func itlPayload(b []byte) (string, []byte, error) {
+	if len(b) < 17 || string(b[:4]) != "hdfm" {
+		return "", nil, ErrNotITL
+	}
+	order := binary.ByteOrder(binary.BigEndian)
+	hl := int(order.Uint32(b[4:]))
+	if hl > len(b) {
+		order = binary.LittleEndian
+		hl = int(order.Uint32(b[4:]))
+	}
+	vl := int(b[16])
+	if hl > len(b) || 17+vl > hl {
+		return "", nil, fmt.Errorf("itl: invalid .itl header length %d", hl)
+	}
+	version := string(b[17 : 17+vl])
+
+	payload := append([]byte(nil), b[hl:]...)
+	n := len(payload)
+	if major, _ := strconv.Atoi(strings.SplitN(version, ".", 2)[0]); major >= 10 && hl >= itlMaxCryptOffset+4 {
+		if max := int(order.Uint32(b[itlMaxCryptOffset:])); max < n {
+			n = max
+		}
+	}
+	n -= n % aes.BlockSize
+	block, err := aes.NewCipher(itlKey)
+	if err != nil {
+		return "", nil, err
+	}
+	for i := 0; i < n; i += aes.BlockSize {
+		block.Decrypt(payload[i:], payload[i:])
+	}
+
+	if len(payload) > 0 && payload[0] == 0x78 {
+		zr, err := zlib.NewReader(bytes.NewReader(payload))
+		if err != nil {
+			return "", nil, fmt.Errorf("itl: decompressing .itl payload: %v", err)
+		}
+		defer zr.Close()
+		if payload, err = ioutil.ReadAll(zr); err != nil {
+			return "", nil, fmt.Errorf("itl: decompressing .itl payload: %v", err)
+		}
+	}
+	return version, payload, nil
+}
+
+// itlParse reads the tracks and playlists from the decrypted payload of an .itl file.  The
+// payload is a sequence of chunks, each beginning with a four character tag and the length
+// of its header.  Container chunks are followed by their contents, so chunks are read in
+// order: strings (hohm chunks) belong to the last track (htim) or playlist (hpim) chunk in
+// the same section (hdsm), and playlist items (hptm) to the last playlist.
+func itlParse(b []byte) (Library, error) {
+	if len(b) < 4 {
+		return Library{}, errors.New("itl: empty .itl payload")
+	}
+	order := binary.ByteOrder(binary.BigEndian)
+	reversed := false
+	switch string(b[:4]) {
+	case "hdsm":
+	case "msdh":
+		order, reversed = binary.LittleEndian, true
+	default:
+		return Library{}, fmt.Errorf("itl: unrecognised .itl payload %q", b[:4])
+	}
+
+	l := Library{Tracks: make(map[string]Track)}
+	u32 := func(c []byte, off int) uint32 {
+		if off+4 > len(c) {
+			return 0
+		}
+		return order.Uint32(c[off:])
+	}
+
+	var track *Track
+	var playlist *Playlist
+	flush := func() {
+		if track != nil {
+			l.Tracks[strconv.Itoa(track.TrackID)] = *track
+			track = nil
+		}
+		if playlist != nil {
+			playlist.PlaylistID = len(l.Playlists) + 1
+			l.Playlists = append(l.Playlists, *playlist)
+			playlist = nil
+		}
+	}
+
+	for off := 0; off < len(b); {
+		if off+12 > len(b) {
+			return Library{}, fmt.Errorf("itl: truncated chunk at offset %d", off)
+		}
+		tag := string(b[off : off+4])
+		if reversed {
+			tag = string([]byte{tag[3], tag[2], tag[1], tag[0]})
+		}
+		n := int(u32(b, off+4))
+		if tag == "hohm" {
+			n = int(u32(b, off+8))
+		}
+		if n < 12 || off+n > len(b) {
+			return Library{}, fmt.Errorf("itl: invalid length %d for %s chunk at offset %d", n, tag, off)
+		}
+		c := b[off : off+n]
+
+		switch tag {
+		case "hdsm":
+			flush()
+
+		case "htim":
+			flush()
+			track = &Track{TrackID: int(u32(c, 16))}
+
+		case "hpim":
+			flush()
+			playlist = &Playlist{Visible: true}
+
+		case "hptm":
+			if playlist != nil {
+				playlist.PlaylistItems = append(playlist.PlaylistItems, PlaylistItem{TrackID: int(u32(c, 24))})
+			}
+
+		case "hohm":
+			typ := u32(c, 12)
+			switch {
+			case track != nil:
+				if set, ok := itlTrackStrings[typ]; ok {
+					set(track, itlString(c, order))
+				}
+			case playlist != nil && typ == itlPlaylistName:
+				playlist.Name = itlString(c, order)
+			}
+		}
+		off += n
+	}
+	flush()
+	return l, nil
+}
+
+// itlString returns the string held in the hohm chunk c: a 4-byte encoding flag at offset
+// 24 (1 for UTF-16, otherwise UTF-8), the length in bytes at offset 28, and the string
+// itself at offset 40.
+func itlString(c []byte, order binary.ByteOrder) string {
+	if len(c) < 40 {
+		return ""
+	}
+	enc := order.Uint32(c[24:])
+	n := int(order.Uint32(c[28:]))
+	if n > len(c)-40 {
+		n = len(c) - 40
+	}
+	s := c[40 : 40+n]
+	if enc != 1 {
+		return string(s)
+	}
+	u := make([]uint16, len(s)/2)
+	for i := range u {
+		u[i] = order.Uint16(s[2*i:])
+	}
+	return string(utf16.Decode(u))
+}
@@ -0,0 +1,197 @@
+// Copyright 2014, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package itl
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/aes"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+// itlChunk returns a chunk of n bytes with the given tag and header length, written in order
+// (with the tag reversed for little-endian), after calling set on it.
+func itlChunk(order binary.ByteOrder, tag string, n int, set func(c []byte)) []byte {
+	c := make([]byte, n)
+	if order == binary.LittleEndian {
+		tag = string([]byte{tag[3], tag[2], tag[1], tag[0]})
+	}
+	copy(c, tag)
+	order.PutUint32(c[4:], uint32(n))
+	if set != nil {
+		set(c)
+	}
+	return c
+}
+
+// itlStringChunk returns a hohm chunk of the given type holding s, encoded as UTF-16 if
+// wide is true.
+func itlStringChunk(order binary.ByteOrder, typ uint32, s string, wide bool) []byte {
+	b := []byte(s)
+	enc := uint32(0)
+	if wide {
+		u := utf16.Encode([]rune(s))
+		b = make([]byte, 2*len(u))
+		for i, r := range u {
+			order.PutUint16(b[2*i:], r)
+		}
+		enc = 1
+	}
+	return itlChunk(order, "hohm", 40+len(b), func(c []byte) {
+		order.PutUint32(c[4:], 24)
+		order.PutUint32(c[8:], uint32(len(c)))
+		order.PutUint32(c[12:], typ)
+		order.PutUint32(c[24:], enc)
+		order.PutUint32(c[28:], uint32(len(b)))
+		copy(c[40:], b)
+	})
+}
+
+// itlTestPayload returns the payload of a library with one track (ID 5) and one playlist.
+func itlTestPayload(order binary.ByteOrder) []byte {
+	var b []byte
+	for _, c := range [][]byte{
+		itlChunk(order, "hdsm", 16, nil),
+		itlChunk(order, "htim", 156, func(c []byte) { order.PutUint32(c[16:], 5) }),
+		itlStringChunk(order, 0x02, "Song", false),
+		itlStringChunk(order, 0x03, "Ålbum", true),
+		itlStringChunk(order, 0x04, "Artist", false),
+		itlStringChunk(order, 0x7f, "ignored", false),
+		itlChunk(order, "hdsm", 16, nil),
+		itlChunk(order, "hpim", 184, nil),
+		itlStringChunk(order, itlPlaylistName, "Mix", true),
+		itlChunk(order, "hptm", 76, func(c []byte) { order.PutUint32(c[24:], 5) }),
+	} {
+		b = append(b, c...)
+	}
+	return b
+}
+
+// itlTestFile returns an .itl file with the given version and payload, compressing it first
+// if compress is true.  If crypt is non-zero only the first crypt bytes are encrypted, as in
+// iTunes 10 and later.
+func itlTestFile(t *testing.T, order binary.ByteOrder, version string, payload []byte, compress bool, crypt int) []byte {
+	if compress {
+		var buf bytes.Buffer
+		zw := zlib.NewWriter(&buf)
+		zw.Write(payload)
+		if err := zw.Close(); err != nil {
+			t.Fatalf("zlib Close() error = %v", err)
+		}
+		payload = buf.Bytes()
+	}
+
+	h := make([]byte, 0x60)
+	copy(h, "hdfm")
+	order.PutUint32(h[4:], uint32(len(h)))
+	h[16] = byte(len(version))
+	copy(h[17:], version)
+	order.PutUint32(h[itlMaxCryptOffset:], uint32(crypt))
+
+	payload = append([]byte(nil), payload...)
+	n := len(payload)
+	if crypt > 0 && crypt < n {
+		n = crypt
+	}
+	n -= n % aes.BlockSize
+	block, err := aes.NewCipher(itlKey)
+	if err != nil {
+		t.Fatalf("aes.NewCipher() error = %v", err)
+	}
+	for i := 0; i < n; i += aes.BlockSize {
+		block.Encrypt(payload[i:], payload[i:])
+	}
+	return append(h, payload...)
+}
+
+func TestReadFromITL(t *testing.T) {
+	tests := []struct {
+		name     string
+		order    binary.ByteOrder
+		version  string
+		compress bool
+		crypt    int
+	}{
+		{"big-endian", binary.BigEndian, "9.2.1", false, 0},
+		{"little-endian", binary.LittleEndian, "12.5.1.21", true, 102400},
+		{"partly encrypted", binary.LittleEndian, "10.7", true, 32},
+	}
+
+	for _, tt := range tests {
+		b := itlTestFile(t, tt.order, tt.version, itlTestPayload(tt.order), tt.compress, tt.crypt)
+		l, err := ReadFromITL(bytes.NewReader(b))
+		if err != nil {
+			t.Errorf("%s: ReadFromITL() error = %v", tt.name, err)
+			continue
+		}
+
+		if l.ApplicationVersion != tt.version {
+			t.Errorf("%s: ApplicationVersion = %q, want %q", tt.name, l.ApplicationVersion, tt.version)
+		}
+		wantTracks := map[string]Track{"5": {TrackID: 5, Name: "Song", Album: "Ålbum", Artist: "Artist"}}
+		if !reflect.DeepEqual(l.Tracks, wantTracks) {
+			t.Errorf("%s: Tracks = %+v, want %+v", tt.name, l.Tracks, wantTracks)
+		}
+		wantPlaylists := []Playlist{{PlaylistID: 1, Name: "Mix", Visible: true, PlaylistItems: []PlaylistItem{{TrackID: 5}}}}
+		if !reflect.DeepEqual(l.Playlists, wantPlaylists) {
+			t.Errorf("%s: Playlists = %+v, want %+v", tt.name, l.Playlists, wantPlaylists)
+		}
+	}
+}
+
+func TestReadFromITLErrors(t *testing.T) {
+	order := binary.BigEndian
+	payload := itlTestPayload(order)
+	file := func(payload []byte) []byte {
+		return itlTestFile(t, order, "9.2.1", payload, false, 0)
+	}
+	badLength := func(off int, n uint32) []byte {
+		b := append([]byte(nil), payload...)
+		order.PutUint32(b[off:], n)
+		return file(b)
+	}
+
+	tests := []struct {
+		name string
+		in   []byte
+		want string
+	}{
+		{"empty", nil, "not an iTunes Library.itl file"},
+		{"not itl", []byte("<?xml version=\"1.0\"?><plist></plist>"), "not an iTunes Library.itl file"},
+		{"header length", append([]byte("hdfm\xff\xff\xff\xff"), make([]byte, 16)...), "invalid .itl header length"},
+		{"version length", func() []byte {
+			b := file(payload)
+			b[16] = 0xff
+			return b
+		}(), "invalid .itl header length"},
+		{"empty payload", file(nil), "empty .itl payload"},
+		{"unrecognised payload", file([]byte("hxxx\x00\x00\x00\x10")), "unrecognised .itl payload"},
+		{"chunk too short", badLength(4, 4), "invalid length 4 for hdsm chunk at offset 0"},
+		{"chunk past end", badLength(20, 1<<20), "invalid length 1048576 for htim chunk at offset 16"},
+		{"hohm past end", badLength(172+8, 1<<31), "for hohm chunk at offset 172"},
+		{"truncated chunk", file(payload[:len(payload)-10]), "invalid length 76 for hptm chunk"},
+		{"truncated header", file(append(payload[:len(payload):len(payload)], "hdsm"...)), "truncated chunk at offset"},
+	}
+
+	for _, tt := range tests {
+		_, err := ReadFromITL(bytes.NewReader(tt.in))
+		if err == nil {
+			t.Errorf("%s: ReadFromITL() error = nil, want error", tt.name)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: ReadFromITL() error = %q, want it to contain %q", tt.name, err, tt.want)
+		}
+	}
+
+	if _, err := ReadFromITL(bytes.NewReader([]byte("hdfm"))); !errors.Is(err, ErrNotITL) {
+		t.Errorf("ReadFromITL(\"hdfm\") error = %v, want ErrNotITL", err)
+	}
+}
@@ -0,0 +1,200 @@
+// Copyright 2014, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package itl
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var trackMapType = reflect.TypeOf(map[string]Track(nil))
+
+// MarshalJSON implements json.Marshaler.  The JSON representation uses camelCase keys derived
+// from the Go field names (e.g. "trackID", "albumArtist", "playlistItems") rather than the plist
+// keys, writes times in RFC 3339 format and omits zero-valued fields.  Tracks are written as an
+// array in ascending TrackID order rather than as an object keyed by ID, so the output for a
+// given Library is always the same.
+func (l Library) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := writeJSON(&buf, reflect.ValueOf(l)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, reading the representation written by
+// MarshalJSON.  The Tracks map is rebuilt from the array of tracks, keyed by TrackID.  Unknown
+// keys are ignored, and values in Extra are decoded as generic JSON values (numbers as
+// json.Number), so marshaling the result gives the same JSON.
+func (l *Library) UnmarshalJSON(b []byte) error {
+	var nl Library
+	if err := readJSON(reflect.ValueOf(&nl).Elem(), b); err != nil {
+		return fmt.Errorf("itl: decoding JSON: %v", err)
+	}
+	*l = nl
+	return nil
+}
+
+// readJSON sets v, which must be settable, from the JSON b written by writeJSON.
+func readJSON(v reflect.Value, b []byte) error {
+	if bytes.Equal(bytes.TrimSpace(b), []byte("null")) {
+		return nil
+	}
+
+	switch {
+	case v.Type() == timeType:
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+
+	case v.Type() == trackMapType:
+		var tracks []Track
+		if err := readJSON(reflect.ValueOf(&tracks).Elem(), b); err != nil {
+			return err
+		}
+		m := make(map[string]Track, len(tracks))
+		for _, t := range tracks {
+			m[strconv.Itoa(t.TrackID)] = t
+		}
+		v.Set(reflect.ValueOf(m))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(b, &fields); err != nil {
+			return err
+		}
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name := jsonFieldName(f.Name)
+			raw, ok := fields[name]
+			if f.PkgPath != "" || !ok {
+				continue
+			}
+			if err := readJSON(v.Field(i), raw); err != nil {
+				return fmt.Errorf("%s: %v", name, err)
+			}
+		}
+		return nil
+
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			break
+		}
+		var elems []json.RawMessage
+		if err := json.Unmarshal(b, &elems); err != nil {
+			return err
+		}
+		s := reflect.MakeSlice(v.Type(), len(elems), len(elems))
+		for i, e := range elems {
+			if err := readJSON(s.Index(i), e); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+		return nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	return dec.Decode(v.Addr().Interface())
+}
+
+// writeJSON writes the JSON representation of v to buf.
+func writeJSON(buf *bytes.Buffer, v reflect.Value) error {
+	v = indirect(v)
+	if !v.IsValid() {
+		buf.WriteString("null")
+		return nil
+	}
+
+	switch {
+	case v.Type() == timeType:
+		buf.WriteString(`"` + v.Interface().(time.Time).Format(time.RFC3339) + `"`)
+		return nil
+
+	case v.Type() == trackMapType:
+		tracks := Library{Tracks: v.Interface().(map[string]Track)}.sortedTracks()
+		return writeJSON(buf, reflect.ValueOf(tracks))
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		buf.WriteByte('{')
+		t := v.Type()
+		first := true
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			fv := v.Field(i)
+			if f.PkgPath != "" || isEmptyValue(fv) {
+				continue
+			}
+			if !first {
+				buf.WriteByte(',')
+			}
+			first = false
+			buf.WriteString(`"` + jsonFieldName(f.Name) + `":`)
+			if err := writeJSON(buf, fv); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+		return nil
+
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			break
+		}
+		buf.WriteByte('[')
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeJSON(buf, v.Index(i)); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+		return nil
+	}
+
+	b, err := json.Marshal(v.Interface())
+	if err != nil {
+		return err
+	}
+	buf.Write(b)
+	return nil
+}
+
+// jsonFieldName converts a Go field name to a camelCase JSON key by lower-casing its
+// leading upper case letters, leaving the last one in place if it starts the next
+// word: "Name" becomes "name", "BPM" becomes "bpm" and "TVShow" becomes "tvShow".
+func jsonFieldName(name string) string {
+	r := []rune(name)
+	n := 0
+	for n < len(r) && unicode.IsUpper(r[n]) {
+		n++
+	}
+	if n > 1 && n < len(r) {
+		n--
+	}
+	return strings.ToLower(string(r[:n])) + string(r[n:])
+}
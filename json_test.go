@@ -0,0 +1,72 @@
+// Copyright 2014, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package itl
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	l, err := Decode(strings.NewReader(testLibraryXML))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	l.Extra = map[string]interface{}{
+		"Custom Date": time.Date(2014, time.June, 1, 2, 3, 4, 0, time.UTC),
+		"Custom List": []interface{}{int64(1), "two", true},
+	}
+
+	b, err := json.Marshal(l)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	var got Library
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	b2, err := json.Marshal(got)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if !bytes.Equal(b, b2) {
+		t.Errorf("json.Marshal() after json.Unmarshal() = %s, want %s", b2, b)
+	}
+
+	if !got.Date.Equal(l.Date) {
+		t.Errorf("Date = %v, want %v", got.Date, l.Date)
+	}
+	if len(got.Tracks) != len(l.Tracks) {
+		t.Fatalf("len(Tracks) = %d, want %d", len(got.Tracks), len(l.Tracks))
+	}
+	tr, want := got.Tracks["1021"], l.Tracks["1021"]
+	if !tr.DateAdded.Equal(want.DateAdded) || !tr.PlayDateUTC.Equal(want.PlayDateUTC) {
+		t.Errorf("DateAdded, PlayDateUTC = %v, %v, want %v, %v", tr.DateAdded, tr.PlayDateUTC, want.DateAdded, want.PlayDateUTC)
+	}
+	if n, ok := tr.Extra["Normalization"].(json.Number); !ok || n != "1530" {
+		t.Errorf("Extra[\"Normalization\"] = %#v, want json.Number(\"1530\")", tr.Extra["Normalization"])
+	}
+
+	if len(got.Playlists) != len(l.Playlists) {
+		t.Fatalf("len(Playlists) = %d, want %d", len(got.Playlists), len(l.Playlists))
+	}
+	for i, p := range got.Playlists {
+		if !bytes.Equal(p.SmartInfo, l.Playlists[i].SmartInfo) {
+			t.Errorf("Playlists[%d].SmartInfo = %x, want %x", i, p.SmartInfo, l.Playlists[i].SmartInfo)
+		}
+		if p.Visible != l.Playlists[i].Visible {
+			t.Errorf("Playlists[%d].Visible = %v, want %v", i, p.Visible, l.Playlists[i].Visible)
+		}
+	}
+	if len(got.Playlists[1].SmartInfo) == 0 {
+		t.Errorf("Playlists[1].SmartInfo is empty, want the Smart Info from testLibraryXML")
+	}
+	if s, ok := got.Extra["Custom Date"].(string); !ok || s != "2014-06-01T02:03:04Z" {
+		t.Errorf("Extra[\"Custom Date\"] = %#v, want \"2014-06-01T02:03:04Z\"", got.Extra["Custom Date"])
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2014, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package itl
+
+import "strings"
+
+// localizedKinds translates the phrases of localized Kind strings which differ from the
+// English ones (for the de, fr, es and ja locales) into their lower-cased English form.
+// Codec names such as "AAC", "AIFF" and "Apple Lossless" aren't translated by iTunes.
+var localizedKinds = strings.NewReplacer(
+	// de
+	"mpeg-4-videodatei", "mpeg-4 video file",
+	"mpeg-audiodatei", "mpeg audio file",
+	"internet-audiostream", "internet audio stream",
+	"hörbuch", "audiobook",
+
+	// fr
+	"fichier vidéo mpeg-4", "mpeg-4 video file",
+	"fichier audio mpeg", "mpeg audio file",
+	"flux audio", "audio stream",
+	"livre audio", "audiobook",
+
+	// es
+	"archivo de vídeo mpeg-4", "mpeg-4 video file",
+	"archivo de audio mpeg", "mpeg audio file",
+	"transmisión de audio", "audio stream",
+	"audiolibro", "audiobook",
+	"libro de audio", "audiobook",
+
+	// ja
+	"mpeg-4 ビデオファイル", "mpeg-4 video file",
+	"mpeg オーディオファイル", "mpeg audio file",
+	"オーディオストリーム", "audio stream",
+	"オーディオブック", "audiobook",
+)
+
+// englishKind returns the lower-cased Kind string k, with localized phrases translated
+// into English so that it can be classified by substring.
+func englishKind(k string) string {
+	return localizedKinds.Replace(strings.ToLower(k))
+}
+
+// kindCodecs maps substrings of lower-cased Kind strings to the short codec names returned
+// by NormalizeKind.  The first match wins, so more specific substrings come first.
+var kindCodecs = []struct {
+	substr, codec string
+}{
+	{"apple lossless", "ALAC"},
+	{"audible", "Audible"},
+	{"aac", "AAC"},
+	{"mpeg-4 video", "MPEG-4 Video"},
+	{"mpeg audio", "MP3"},
+	{"mp3", "MP3"},
+	{"aiff", "AIFF"},
+	{"wav", "WAV"},
+	{"flac", "FLAC"},
+	{"quicktime", "QuickTime"},
+	{"audio stream", "Stream"},
+}
+
+// NormalizeKind returns the short codec name for the Kind string k, ignoring
+// qualifiers such as "Purchased", "Protected" or "Matched":
+//
+//	"Apple Lossless audio file"           ALAC
+//	"Audible file"                        Audible
+//	"AAC audio file"                      AAC (also purchased, protected, Apple Music and audiobook files)
+//	"MPEG-4 video file"                   MPEG-4 Video
+//	"MPEG audio file"                     MP3
+//	"AIFF audio file"                     AIFF
+//	"WAV audio file"                      WAV
+//	"FLAC audio file"                     FLAC
+//	"QuickTime movie file"                QuickTime
+//	"Internet audio stream"               Stream
+//
+// German, French, Spanish and Japanese Kind strings are also recognised.  Kind strings which
+// aren't recognised are returned unchanged.
+func NormalizeKind(k string) string {
+	lk := englishKind(k)
+	for _, c := range kindCodecs {
+		if strings.Contains(lk, c.substr) {
+			return c.codec
+		}
+	}
+	return k
+}
+
+// KindDistribution returns the number of tracks with each Kind.  Tracks without a
+// Kind are counted under "".
+func (l Library) KindDistribution() map[string]int {
+	m := make(map[string]int)
+	for _, t := range l.Tracks {
+		m[t.Kind]++
+	}
+	return m
+}
+
+// CodecDistribution returns the number of tracks with each codec, as given by
+// normalizing their Kind with NormalizeKind.
+func (l Library) CodecDistribution() map[string]int {
+	m := make(map[string]int)
+	for _, t := range l.Tracks {
+		m[NormalizeKind(t.Kind)]++
+	}
+	return m
+}
@@ -0,0 +1,60 @@
+// Copyright 2014, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package itl
+
+import (
+	"sort"
+	"strconv"
+	"time"
+)
+
+// GetTrack returns the Track with the given TrackID, and true if it was found in the
+// library.  This is the canonical way to resolve a PlaylistItem to its Track.
+func (l Library) GetTrack(id int) (Track, bool) {
+	t, ok := l.Tracks[strconv.Itoa(id)]
+	return t, ok
+}
+
+// EachTrack calls fn for each track in the library in ascending TrackID order.  If fn
+// returns a non-nil error then iteration stops and the error is returned.
+func (l Library) EachTrack(fn func(Track) error) error {
+	for _, t := range l.sortedTracks() {
+		if err := fn(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TotalDuration returns the total duration of all the tracks in the library.
+func (l Library) TotalDuration() time.Duration {
+	var d time.Duration
+	for _, t := range l.Tracks {
+		d += t.Duration()
+	}
+	return d
+}
+
+// sortedTracks returns the tracks in the library in ascending TrackID order.  Tracks with
+// the same TrackID (see KeyMismatches) are ordered by their key in Tracks, so the order
+// doesn't depend on map iteration.
+func (l Library) sortedTracks() []Track {
+	keys := make([]string, 0, len(l.Tracks))
+	for k := range l.Tracks {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		ti, tj := l.Tracks[keys[i]].TrackID, l.Tracks[keys[j]].TrackID
+		if ti != tj {
+			return ti < tj
+		}
+		return keys[i] < keys[j]
+	})
+	tracks := make([]Track, len(keys))
+	for i, k := range keys {
+		tracks[i] = l.Tracks[k]
+	}
+	return tracks
+}
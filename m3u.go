@@ -0,0 +1,29 @@
+// Copyright 2014, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package itl
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// WriteM3U writes the playlist p to w as an extended M3U playlist.  Each track is written
+// as an #EXTINF line followed by its local file path.  Tracks which are missing from
+// the library, or whose Location is empty or not a local file (such as remote streams),
+// are skipped.
+func (l Library) WriteM3U(w io.Writer, p Playlist) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "#EXTM3U")
+	for _, t := range l.PlaylistTracks(p) {
+		path, err := t.LocalPath()
+		if err != nil || path == "" {
+			continue
+		}
+		fmt.Fprintf(bw, "#EXTINF:%d,%s - %s\n", t.TotalTime/1000, t.Artist, t.Name)
+		fmt.Fprintln(bw, path)
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,92 @@
+// Copyright 2014, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package itl
+
+import (
+	"strconv"
+	"strings"
+)
+
+// MediaKind is the type of media of a track, see Track.MediaKind.
+type MediaKind int
+
+// Media kinds returned by Track.MediaKind.
+const (
+	Music MediaKind = iota
+	Movie
+	TVShow
+	Podcast
+	Audiobook
+	MusicVideo
+	ITunesU
+)
+
+var mediaKindNames = [...]string{
+	Music:      "Music",
+	Movie:      "Movie",
+	TVShow:     "TV Show",
+	Podcast:    "Podcast",
+	Audiobook:  "Audiobook",
+	MusicVideo: "Music Video",
+	ITunesU:    "iTunes U",
+}
+
+func (k MediaKind) String() string {
+	if k >= 0 && int(k) < len(mediaKindNames) {
+		return mediaKindNames[k]
+	}
+	return "MediaKind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// MediaKind classifies the track using its media flags and Kind.  The first match in
+// the following order is returned:
+//
+//	ITunesU     the iTunesU flag is set
+//	Podcast     the Podcast flag is set
+//	Audiobook   IsAudiobook is true (an audiobook Kind or Genre)
+//	TVShow      the TVShow flag is set
+//	MusicVideo  the MusicVideo flag is set
+//	Movie       the Movie or HasVideo flag is set
+//	Music       otherwise
+func (t Track) MediaKind() MediaKind {
+	switch {
+	case t.ITunesU:
+		return ITunesU
+	case t.Podcast:
+		return Podcast
+	case t.IsAudiobook():
+		return Audiobook
+	case t.TVShow:
+		return TVShow
+	case t.MusicVideo:
+		return MusicVideo
+	case t.Movie, t.HasVideo:
+		return Movie
+	}
+	return Music
+}
+
+// IsAudiobook returns true if the track is an audiobook: its Kind is an audiobook kind (e.g.
+// "Audible file" or "AAC audio book file") or its Genre is "Audiobook" or "Audiobooks", and
+// it isn't a podcast or iTunes U track.
+func (t Track) IsAudiobook() bool {
+	if t.Podcast || t.ITunesU {
+		return false
+	}
+	return isAudiobookKind(t.Kind) || strings.EqualFold(t.Genre, "Audiobook") || strings.EqualFold(t.Genre, "Audiobooks")
+}
+
+// IsVoiceMemo returns true if the track is a voice memo, which iTunes imports from devices
+// with the Genre "Voice Memo".
+func (t Track) IsVoiceMemo() bool {
+	return strings.EqualFold(t.Genre, "Voice Memo")
+}
+
+// isAudiobookKind returns true if the Kind string k describes an audiobook.  Localized Kind
+// strings are recognised, see NormalizeKind.
+func isAudiobookKind(k string) bool {
+	k = englishKind(k)
+	return strings.Contains(k, "audio book") || strings.Contains(k, "audiobook") || strings.Contains(k, "audible")
+}
@@ -0,0 +1,33 @@
+// Copyright 2014, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package itl
+
+import "testing"
+
+func TestMediaKind(t *testing.T) {
+	tests := []struct {
+		name  string
+		track Track
+		want  MediaKind
+	}{
+		{"music", Track{Kind: "MPEG audio file", Genre: "Rock"}, Music},
+		{"audiobook kind", Track{Kind: "Audible file"}, Audiobook},
+		{"audiobook genre", Track{Kind: "MPEG audio file", Genre: "Audiobooks"}, Audiobook},
+		{"podcast audiobook genre", Track{Kind: "MPEG audio file", Genre: "Audiobook", Podcast: true}, Podcast},
+		{"itunes u", Track{Kind: "AAC audio book file", ITunesU: true}, ITunesU},
+		{"tv show", Track{Kind: "MPEG-4 video file", TVShow: true, HasVideo: true}, TVShow},
+		{"music video", Track{Kind: "MPEG-4 video file", MusicVideo: true, HasVideo: true}, MusicVideo},
+		{"movie", Track{Kind: "MPEG-4 video file", HasVideo: true}, Movie},
+	}
+
+	for _, tt := range tests {
+		if got := tt.track.MediaKind(); got != tt.want {
+			t.Errorf("%s: MediaKind() = %v, want %v", tt.name, got, tt.want)
+		}
+		if got, want := tt.track.IsAudiobook(), tt.want == Audiobook; got != want {
+			t.Errorf("%s: IsAudiobook() = %v, want %v", tt.name, got, want)
+		}
+	}
+}
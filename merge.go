@@ -0,0 +1,160 @@
+// Copyright 2014, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package itl
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// MergeStrategy resolves a conflict in Merge between track a (from the first library) and
+// track b (from the second) which have the same PersistentID, returning the track to keep.
+// PreferA, PreferB and PreferNewer are provided, or any other function can be used.
+type MergeStrategy func(a, b Track) Track
+
+// PreferA is a MergeStrategy which keeps the track from the first library.
+func PreferA(a, b Track) Track { return a }
+
+// PreferB is a MergeStrategy which keeps the track from the second library.
+func PreferB(a, b Track) Track { return b }
+
+// PreferNewer is a MergeStrategy which keeps the track with the latest DateModified,
+// preferring a if they are the same.
+func PreferNewer(a, b Track) Track {
+	if b.DateModified.After(a.DateModified) {
+		return b
+	}
+	return a
+}
+
+// Merge combines the libraries a and b.  The top-level library values are taken from a.
+//
+// Tracks are matched by PersistentID, and when a track is in both libraries strategy is
+// used to choose which to keep.  Tracks in b which share a PersistentID are also merged with
+// strategy, so the result has one track for each PersistentID.  Tracks without a
+// PersistentID are never matched.  Tracks keep their TrackID from a, and tracks which are
+// only in b are given new TrackIDs following the largest in a, so IDs never collide.
+// PlaylistItems (and GeniusTrackIDs) from b are rewritten to use the new IDs, and items which
+// reference tracks missing from b are dropped.
+//
+// Playlists are matched by PlaylistPersistentID (and the master playlists of a and b are
+// always matched).  Matched playlists keep the values from a, with any items from b not
+// already in the playlist appended.  Playlists which are only in b are appended and given
+// new PlaylistIDs following the largest in a.  Persistent IDs are not changed, so
+// ParentPersistentID references (and hence the folder hierarchy) are preserved for both
+// libraries.
+func Merge(a, b Library, strategy MergeStrategy) Library {
+	m := a
+	m.Tracks = make(map[string]Track, len(a.Tracks)+len(b.Tracks))
+	m.Playlists = nil
+
+	byPID := make(map[string]int)
+	maxID := 0
+	for _, t := range a.sortedTracks() {
+		m.Tracks[strconv.Itoa(t.TrackID)] = t
+		if t.PersistentID != "" {
+			byPID[t.PersistentID] = t.TrackID
+		}
+		if t.TrackID > maxID {
+			maxID = t.TrackID
+		}
+	}
+
+	remapB := make(map[int]int, len(b.Tracks))
+	for _, t := range b.sortedTracks() {
+		if id, ok := byPID[t.PersistentID]; ok {
+			k := strconv.Itoa(id)
+			mt := strategy(m.Tracks[k], t)
+			mt.TrackID = id
+			m.Tracks[k] = mt
+			remapB[t.TrackID] = id
+			continue
+		}
+		maxID++
+		remapB[t.TrackID] = maxID
+		t.TrackID = maxID
+		m.Tracks[strconv.Itoa(maxID)] = t
+		if t.PersistentID != "" {
+			byPID[t.PersistentID] = maxID
+		}
+	}
+
+	playlists := make(map[string]int)
+	master := -1
+	maxPlaylistID := 0
+	for _, p := range a.Playlists {
+		p.PlaylistItems = append([]PlaylistItem(nil), p.PlaylistItems...)
+		if p.PlaylistPersistentID != "" {
+			playlists[p.PlaylistPersistentID] = len(m.Playlists)
+		}
+		if p.Master && master < 0 {
+			master = len(m.Playlists)
+		}
+		if p.PlaylistID > maxPlaylistID {
+			maxPlaylistID = p.PlaylistID
+		}
+		m.Playlists = append(m.Playlists, p)
+	}
+
+	for _, p := range b.Playlists {
+		items := make([]PlaylistItem, 0, len(p.PlaylistItems))
+		for _, item := range p.PlaylistItems {
+			if id, ok := remapB[item.TrackID]; ok {
+				item.TrackID = id
+				items = append(items, item)
+			}
+		}
+
+		i, ok := playlists[p.PlaylistPersistentID]
+		if !ok || p.PlaylistPersistentID == "" {
+			i, ok = master, p.Master && master >= 0
+		}
+		if ok {
+			mp := &m.Playlists[i]
+			seen := make(map[int]bool, len(mp.PlaylistItems))
+			for _, item := range mp.PlaylistItems {
+				seen[item.TrackID] = true
+			}
+			for _, item := range items {
+				if !seen[item.TrackID] {
+					seen[item.TrackID] = true
+					mp.PlaylistItems = append(mp.PlaylistItems, item)
+				}
+			}
+			continue
+		}
+
+		maxPlaylistID++
+		p.PlaylistID = maxPlaylistID
+		p.PlaylistItems = items
+		p.GeniusTrackID = remapB[p.GeniusTrackID]
+		if p.PlaylistPersistentID != "" {
+			playlists[p.PlaylistPersistentID] = len(m.Playlists)
+		}
+		m.Playlists = append(m.Playlists, p)
+	}
+	return m
+}
+
+// ReadAndMergeFiles reads the libraries in the named files (see ReadFromFile) and merges
+// them in order with Merge, using PreferNewer to choose between versions of the same track.
+// The top-level library values are those of the first file, tracks are matched by
+// PersistentID and given non-conflicting TrackIDs, and playlists are de-duplicated by
+// PlaylistPersistentID.  Returns an empty Library if no paths are given.
+func ReadAndMergeFiles(paths ...string) (Library, error) {
+	var m Library
+	for i, path := range paths {
+		l, err := ReadFromFile(path)
+		if err != nil {
+			return Library{}, fmt.Errorf("itl: reading %s: %w", path, err)
+		}
+		if i == 0 {
+			m = l
+			continue
+		}
+		m = Merge(m, l, PreferNewer)
+	}
+	return m, nil
+}
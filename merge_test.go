@@ -0,0 +1,124 @@
+// Copyright 2014, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package itl
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+var (
+	mergeOld = time.Date(2014, time.January, 1, 0, 0, 0, 0, time.UTC)
+	mergeNew = time.Date(2014, time.June, 1, 0, 0, 0, 0, time.UTC)
+)
+
+func mergeTestLibraries() (Library, Library) {
+	a := Library{
+		MajorVersion: 1,
+		Tracks: map[string]Track{
+			"1": {TrackID: 1, PersistentID: "AAAA", Name: "a", DateModified: mergeOld},
+			"2": {TrackID: 2, Name: "no persistent id"},
+		},
+		Playlists: []Playlist{
+			{PlaylistID: 1, PlaylistPersistentID: "M1", Name: "Library", Master: true, PlaylistItems: []PlaylistItem{{TrackID: 1}, {TrackID: 2}}},
+			{PlaylistID: 2, PlaylistPersistentID: "S", Name: "Shared", PlaylistItems: []PlaylistItem{{TrackID: 1}}},
+			{PlaylistID: 3, PlaylistPersistentID: "F", Name: "Folder", Folder: true},
+		},
+	}
+	b := Library{
+		MajorVersion: 2,
+		Tracks: map[string]Track{
+			"1": {TrackID: 1, PersistentID: "AAAA", Name: "b", DateModified: mergeNew},
+			"7": {TrackID: 7, PersistentID: "BBBB", Name: "seven"},
+			"8": {TrackID: 8, PersistentID: "BBBB", Name: "eight"},
+			"9": {TrackID: 9, Name: "nine"},
+		},
+		Playlists: []Playlist{
+			{PlaylistID: 5, PlaylistPersistentID: "M2", Name: "Library", Master: true, PlaylistItems: []PlaylistItem{{TrackID: 1}, {TrackID: 7}, {TrackID: 8}, {TrackID: 9}, {TrackID: 99}}},
+			{PlaylistID: 6, PlaylistPersistentID: "S", Name: "Shared (b)", PlaylistItems: []PlaylistItem{{TrackID: 7}, {TrackID: 1}}},
+			{PlaylistID: 7, PlaylistPersistentID: "C", ParentPersistentID: "F", Name: "Child", GeniusTrackID: 7, PlaylistItems: []PlaylistItem{{TrackID: 9}, {TrackID: 99}}},
+		},
+	}
+	return a, b
+}
+
+func mergeItems(ids ...int) []PlaylistItem {
+	items := make([]PlaylistItem, len(ids))
+	for i, id := range ids {
+		items[i] = PlaylistItem{TrackID: id}
+	}
+	return items
+}
+
+func TestMerge(t *testing.T) {
+	a, b := mergeTestLibraries()
+	m := Merge(a, b, PreferNewer)
+
+	if m.MajorVersion != 1 {
+		t.Errorf("MajorVersion = %d, want 1", m.MajorVersion)
+	}
+
+	wantTracks := map[string]Track{
+		"1": {TrackID: 1, PersistentID: "AAAA", Name: "b", DateModified: mergeNew},
+		"2": {TrackID: 2, Name: "no persistent id"},
+		"3": {TrackID: 3, PersistentID: "BBBB", Name: "seven"},
+		"4": {TrackID: 4, Name: "nine"},
+	}
+	if !reflect.DeepEqual(m.Tracks, wantTracks) {
+		t.Errorf("Tracks = %+v, want %+v", m.Tracks, wantTracks)
+	}
+
+	wantPlaylists := []Playlist{
+		{PlaylistID: 1, PlaylistPersistentID: "M1", Name: "Library", Master: true, PlaylistItems: mergeItems(1, 2, 3, 4)},
+		{PlaylistID: 2, PlaylistPersistentID: "S", Name: "Shared", PlaylistItems: mergeItems(1, 3)},
+		{PlaylistID: 3, PlaylistPersistentID: "F", Name: "Folder", Folder: true},
+		{PlaylistID: 4, PlaylistPersistentID: "C", ParentPersistentID: "F", Name: "Child", GeniusTrackID: 3, PlaylistItems: mergeItems(4)},
+	}
+	if !reflect.DeepEqual(m.Playlists, wantPlaylists) {
+		t.Errorf("Playlists = %+v, want %+v", m.Playlists, wantPlaylists)
+	}
+
+	tree := m.PlaylistTree()
+	if len(tree) != 3 || len(tree[2].Children) != 1 || tree[2].Children[0].Playlist.Name != "Child" {
+		t.Errorf("PlaylistTree() doesn't have Child in Folder: %+v", tree)
+	}
+
+	a2, _ := mergeTestLibraries()
+	if !reflect.DeepEqual(a, a2) {
+		t.Errorf("Merge() modified a: %+v", a)
+	}
+}
+
+func TestMergeStrategy(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy MergeStrategy
+		bDate    time.Time
+		want     string
+	}{
+		{"PreferA", PreferA, mergeNew, "a"},
+		{"PreferB", PreferB, mergeOld, "b"},
+		{"PreferNewer b newer", PreferNewer, mergeNew, "b"},
+		{"PreferNewer b older", PreferNewer, mergeOld.Add(-time.Hour), "a"},
+		{"PreferNewer same", PreferNewer, mergeOld, "a"},
+	}
+
+	for _, tt := range tests {
+		a, b := mergeTestLibraries()
+		bt := b.Tracks["1"]
+		bt.DateModified = tt.bDate
+		b.Tracks["1"] = bt
+
+		m := Merge(a, b, tt.strategy)
+		got, ok := m.GetTrack(1)
+		if !ok || got.Name != tt.want || got.TrackID != 1 {
+			t.Errorf("%s: GetTrack(1) = %q (TrackID %d), %v, want %q (TrackID 1), true", tt.name, got.Name, got.TrackID, ok, tt.want)
+		}
+		if len(m.Tracks) != 4 {
+			t.Errorf("%s: len(Tracks) = %d, want 4", tt.name, len(m.Tracks))
+		}
+	}
+}
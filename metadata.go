@@ -0,0 +1,52 @@
+// Copyright 2014, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package itl
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// DefaultRequiredFields are the Track fields checked by IncompleteMetadata when no fields
+// are given.
+var DefaultRequiredFields = []string{"Name", "Artist", "Album"}
+
+// IncompleteMetadata returns the tracks which are missing any of the required fields, in
+// ascending TrackID order.  Fields are named by their Go field name (such as "AlbumArtist")
+// or plist key (such as "Album Artist"), and default to DefaultRequiredFields.  A string field
+// is missing if it is empty or only contains white space, and any other field if it has its
+// zero value.  An error is returned if a field doesn't exist.
+func (l Library) IncompleteMetadata(required ...string) ([]Track, error) {
+	if len(required) == 0 {
+		required = DefaultRequiredFields
+	}
+	fields := make([][]int, len(required))
+	for i, name := range required {
+		f, ok := trackField(name)
+		if !ok {
+			return nil, fmt.Errorf("itl: no track field %q", name)
+		}
+		fields[i] = f.Index
+	}
+
+	incomplete := l.FilterTracks(func(t Track) bool {
+		v := reflect.ValueOf(t)
+		for _, index := range fields {
+			fv := v.FieldByIndex(index)
+			if fv.Kind() == reflect.String {
+				if strings.TrimSpace(fv.String()) == "" {
+					return true
+				}
+				continue
+			}
+			if isEmptyValue(fv) {
+				return true
+			}
+		}
+		return false
+	})
+	return incomplete, nil
+}
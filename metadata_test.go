@@ -0,0 +1,35 @@
+// Copyright 2014, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package itl
+
+import "testing"
+
+func TestIncompleteMetadata(t *testing.T) {
+	l := Library{Tracks: map[string]Track{
+		"1": {TrackID: 1, Name: "Kashmir", Artist: "Led Zeppelin", Album: "Physical Graffiti"},
+		"2": {TrackID: 2, Name: "Untitled", Artist: " ", Album: "Demos"},
+		"3": {TrackID: 3, Name: "Heroes", Artist: "David Bowie", Album: "Heroes"},
+	}}
+
+	got, err := l.IncompleteMetadata()
+	if err != nil {
+		t.Fatalf("IncompleteMetadata() error = %v", err)
+	}
+	if len(got) != 1 || got[0].TrackID != 2 {
+		t.Errorf("IncompleteMetadata() = %v, want track 2", got)
+	}
+
+	got, err = l.IncompleteMetadata("Year")
+	if err != nil {
+		t.Fatalf("IncompleteMetadata(\"Year\") error = %v", err)
+	}
+	if len(got) != 3 {
+		t.Errorf("IncompleteMetadata(\"Year\") returned %d tracks, want 3", len(got))
+	}
+
+	if _, err := l.IncompleteMetadata("Name", "Mood"); err == nil || err.Error() != `itl: no track field "Mood"` {
+		t.Errorf("IncompleteMetadata(\"Mood\") error = %v, want no track field", err)
+	}
+}
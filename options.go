@@ -0,0 +1,104 @@
+// Copyright 2014, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package itl
+
+// DecodeOptions configures a Decoder.  The zero value decodes strictly in the same
+// way as ReadFromXML.
+type DecodeOptions struct {
+	// OnDateError determines what happens when a <date> value can't be parsed.
+	OnDateError DateErrorPolicy
+
+	// DetectDuplicateTracks reports a Warning for each key which occurs more than once
+	// in the Tracks dict (after canonicalization if CanonicalTrackKeys is set).  Later
+	// tracks replace earlier ones with the same key, as they always do.
+	DetectDuplicateTracks bool
+
+	// CanonicalTrackKeys rewrites Tracks keys into canonical decimal form, so that keys
+	// such as "0123" and " 123" are stored (and compared) as "123".
+	CanonicalTrackKeys bool
+
+	// SkipTracks and SkipPlaylists discard the Tracks dict and Playlists array, leaving
+	// Library.Tracks and Library.Playlists nil.  Skipped values must still be read and
+	// scanned, as a plist can't be navigated without parsing it, but nothing in them is
+	// decoded or stored, which makes reading just the top-level values (or just the
+	// playlists) of a large library much faster.  SkipTracks is ignored by DecodeTracks.
+	SkipTracks    bool
+	SkipPlaylists bool
+
+	// Progress, if non-nil, is called with the number of tracks decoded so far after
+	// every ProgressInterval tracks, and once more with the total at the end of the Tracks
+	// dict.  It is called synchronously from the goroutine which is decoding (never
+	// concurrently), so decoding waits for it to return.  It isn't called for skipped tracks.
+	Progress func(tracksParsed int)
+
+	// Strict rejects input which doesn't look like an iTunes library, rather than decoding
+	// it into a mostly empty Library: the root value must be a <dict> whose first key is
+	// Major Version (as iTunes always writes it), and which has a Tracks key.  The first
+	// two checks are made before anything else is decoded, so unrelated plists fail fast.
+	// The Tracks key can be anywhere in the root dict, so its absence is only reported
+	// once the whole document has been decoded.
+	Strict bool
+}
+
+// ProgressInterval is the number of tracks decoded between calls to DecodeOptions.Progress.
+const ProgressInterval = 1000
+
+// DateErrorPolicy determines how a Decoder handles dates which can't be parsed.
+type DateErrorPolicy int
+
+// Policies for DecodeOptions.OnDateError.
+const (
+	// DateErrorFail stops decoding and returns an error.
+	DateErrorFail DateErrorPolicy = iota
+
+	// DateErrorZero sets the value to the zero time.Time and continues.
+	DateErrorZero
+
+	// DateErrorSkip ignores the value, as if its key was not present, and continues.
+	// For time.Time fields (such as Track.DateAdded) this is the same as DateErrorZero,
+	// as the field is left at its zero value.  The policies only differ for values which
+	// aren't struct fields: with DateErrorSkip an unparsable date is left out of Extra
+	// (and other maps), and is nil in an array, rather than being the zero time.Time.
+	DateErrorSkip
+)
+
+// DateError describes a date which could not be parsed.
+type DateError struct {
+	// Path is the location of the value in the plist, as a sequence of keys and array
+	// indices separated by "/", for instance "Tracks/1234/Date Added".
+	Path string
+
+	// Value is the text of the <date> element.
+	Value string
+
+	Err error
+}
+
+func (e DateError) Error() string {
+	return "itl: " + e.Path + ": " + e.Err.Error()
+}
+
+// Result is the result of DecodeWithOptions.
+type Result struct {
+	Library Library
+
+	// DateErrors lists the dates which could not be parsed, when
+	// DecodeOptions.OnDateError is not DateErrorFail.
+	DateErrors []DateError
+
+	// Warnings lists other problems found while decoding, such as duplicate track IDs.
+	Warnings []Warning
+}
+
+// Warning describes a problem found while decoding which did not stop decoding.
+type Warning struct {
+	// Path is the location of the problem in the plist, see DateError.Path.
+	Path    string
+	Message string
+}
+
+func (w Warning) String() string {
+	return w.Path + ": " + w.Message
+}
@@ -0,0 +1,38 @@
+// Copyright 2014, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package itl
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// parsePersistentID parses a persistent ID, which is a 64-bit value written as 16
+// hexadecimal digits.
+func parsePersistentID(id string) (uint64, error) {
+	if len(id) != 16 {
+		return 0, fmt.Errorf("itl: invalid persistent ID %q: must be 16 hex digits", id)
+	}
+	n, err := strconv.ParseUint(id, 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("itl: invalid persistent ID %q: %v", id, err)
+	}
+	return n, nil
+}
+
+// PersistentIDValue returns the PersistentID of the track as a 64-bit integer.
+func (t Track) PersistentIDValue() (uint64, error) {
+	return parsePersistentID(t.PersistentID)
+}
+
+// PersistentIDValue returns the PlaylistPersistentID of the playlist as a 64-bit integer.
+func (p Playlist) PersistentIDValue() (uint64, error) {
+	return parsePersistentID(p.PlaylistPersistentID)
+}
+
+// PersistentIDValue returns the LibraryPersistentID of the library as a 64-bit integer.
+func (l Library) PersistentIDValue() (uint64, error) {
+	return parsePersistentID(l.LibraryPersistentID)
+}
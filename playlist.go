@@ -0,0 +1,263 @@
+// Copyright 2014, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package itl
+
+// PlaylistTracks returns the Track for each PlaylistItem in p, in playlist order.
+// Items which reference a TrackID that is not in the library (which is common in
+// exported libraries) are skipped, so the result may be shorter than p.PlaylistItems.
+func (l Library) PlaylistTracks(p Playlist) []Track {
+	tracks := make([]Track, 0, len(p.PlaylistItems))
+	for _, item := range p.PlaylistItems {
+		if t, ok := l.GetTrack(item.TrackID); ok {
+			tracks = append(tracks, t)
+		}
+	}
+	return tracks
+}
+
+// GetPlaylist returns the playlist with the given PlaylistPersistentID, and true if it
+// was found in the library.
+func (l Library) GetPlaylist(persistentID string) (Playlist, bool) {
+	for _, p := range l.Playlists {
+		if p.PlaylistPersistentID == persistentID {
+			return p, true
+		}
+	}
+	return Playlist{}, false
+}
+
+// GetPlaylistByName returns the first playlist with the given name, and true if one was
+// found in the library.  Playlist names are not unique: use GetPlaylist to find a
+// specific playlist.
+func (l Library) GetPlaylistByName(name string) (Playlist, bool) {
+	for _, p := range l.Playlists {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Playlist{}, false
+}
+
+// PlaylistNode is a playlist in the folder hierarchy returned by Library.PlaylistTree.
+type PlaylistNode struct {
+	Playlist Playlist
+	Children []*PlaylistNode
+}
+
+// PlaylistTree returns the playlist folder hierarchy of the library as shown in the iTunes
+// sidebar.  Playlists without a parent, or whose parent is missing, are roots.  Children
+// are in the same order as in l.Playlists.  If the parent references form a cycle then it
+// is broken by making the first playlist (in l.Playlists order) not reachable from a root
+// into a root itself.
+func (l Library) PlaylistTree() []*PlaylistNode {
+	nodes := make([]*PlaylistNode, len(l.Playlists))
+	index := make(map[string]int, len(l.Playlists))
+	for i, p := range l.Playlists {
+		nodes[i] = &PlaylistNode{Playlist: p}
+		if p.PlaylistPersistentID != "" {
+			if _, ok := index[p.PlaylistPersistentID]; !ok {
+				index[p.PlaylistPersistentID] = i
+			}
+		}
+	}
+
+	parent := make([]int, len(nodes))
+	children := make([][]int, len(nodes))
+	for i, p := range l.Playlists {
+		parent[i] = -1
+		if j, ok := index[p.ParentPersistentID]; ok && p.ParentPersistentID != "" && j != i {
+			parent[i] = j
+			children[j] = append(children[j], i)
+		}
+	}
+
+	reached := make([]bool, len(nodes))
+	var attach func(i int)
+	attach = func(i int) {
+		reached[i] = true
+		for _, c := range children[i] {
+			if !reached[c] {
+				nodes[i].Children = append(nodes[i].Children, nodes[c])
+				attach(c)
+			}
+		}
+	}
+
+	var roots []*PlaylistNode
+	for i := range nodes {
+		if parent[i] < 0 {
+			roots = append(roots, nodes[i])
+			attach(i)
+		}
+	}
+	// Anything not yet reached is part of (or descends from) a cycle.
+	for i := range nodes {
+		if !reached[i] {
+			roots = append(roots, nodes[i])
+			attach(i)
+		}
+	}
+	return roots
+}
+
+// OrphanTracks returns the tracks which are not in any user playlist, in ascending TrackID
+// order.  The master playlist, folders and the built-in playlists (those with a
+// DistinguishedKind, such as Music or Podcasts) are not considered user playlists.  The
+// Visible flag isn't used, as iTunes only writes it for hidden playlists.
+func (l Library) OrphanTracks() []Track {
+	referenced := make(map[int]bool)
+	for _, p := range l.Playlists {
+		if !p.IsUserCreated() || p.IsFolder() {
+			continue
+		}
+		for _, item := range p.PlaylistItems {
+			referenced[item.TrackID] = true
+		}
+	}
+	return l.FilterTracks(func(t Track) bool {
+		return !referenced[t.TrackID]
+	})
+}
+
+// PlaylistsContaining returns the playlists which contain the track with the given TrackID,
+// in library order.  Folders are not included: iTunes lists the tracks of all the playlists
+// in a folder as items of the folder itself.  To find the playlists of many tracks, use
+// PlaylistMembership which builds the result for every track in one pass.
+func (l Library) PlaylistsContaining(trackID int) []Playlist {
+	var playlists []Playlist
+	for _, p := range l.Playlists {
+		if p.Folder {
+			continue
+		}
+		for _, item := range p.PlaylistItems {
+			if item.TrackID == trackID {
+				playlists = append(playlists, p)
+				break
+			}
+		}
+	}
+	return playlists
+}
+
+// PlaylistMembership returns the playlists containing each track, keyed by TrackID, as
+// PlaylistsContaining would return them.
+func (l Library) PlaylistMembership() map[int][]Playlist {
+	m := make(map[int][]Playlist)
+	for _, p := range l.Playlists {
+		if p.Folder {
+			continue
+		}
+		seen := make(map[int]bool, len(p.PlaylistItems))
+		for _, item := range p.PlaylistItems {
+			if !seen[item.TrackID] {
+				seen[item.TrackID] = true
+				m[item.TrackID] = append(m[item.TrackID], p)
+			}
+		}
+	}
+	return m
+}
+
+// IsSmart returns true if p is a smart playlist, that is it has Smart Criteria.
+func (p Playlist) IsSmart() bool {
+	return len(p.SmartCriteria) > 0
+}
+
+// IsFolder returns true if p is a playlist folder.
+func (p Playlist) IsFolder() bool {
+	return p.Folder
+}
+
+// IsUserCreated returns true if p was created by the user: it is not the master playlist and
+// not one of the built-in playlists (such as Music or Podcasts), which have a non-zero
+// DistinguishedKind.  Folders and smart playlists can be user created.
+func (p Playlist) IsUserCreated() bool {
+	return !p.Master && p.DistinguishedKind == 0
+}
+
+// FlattenPlaylist returns the tracks of p.  If p is a folder these are the tracks of every
+// playlist inside it, including those in nested folders, visited depth first in the order
+// they appear in l.Playlists (so in the same order as PlaylistTree); each track is included
+// once, at its first occurrence.  Otherwise the result is the same as PlaylistTracks.
+// Items which reference missing tracks are skipped, and cycles in the folder hierarchy are
+// ignored.
+func (l Library) FlattenPlaylist(p Playlist) []Track {
+	if !p.IsFolder() {
+		return l.PlaylistTracks(p)
+	}
+
+	children := make(map[string][]Playlist)
+	for _, c := range l.Playlists {
+		if c.ParentPersistentID != "" {
+			children[c.ParentPersistentID] = append(children[c.ParentPersistentID], c)
+		}
+	}
+
+	var tracks []Track
+	seenTracks := make(map[int]bool)
+	seenFolders := make(map[string]bool)
+	var walk func(f Playlist)
+	walk = func(f Playlist) {
+		if seenFolders[f.PlaylistPersistentID] {
+			return
+		}
+		seenFolders[f.PlaylistPersistentID] = true
+		for _, c := range children[f.PlaylistPersistentID] {
+			if c.IsFolder() {
+				walk(c)
+				continue
+			}
+			for _, t := range l.PlaylistTracks(c) {
+				if !seenTracks[t.TrackID] {
+					seenTracks[t.TrackID] = true
+					tracks = append(tracks, t)
+				}
+			}
+		}
+	}
+	if p.PlaylistPersistentID != "" {
+		walk(p)
+	}
+	return tracks
+}
+
+// Values of Playlist.DistinguishedKind for the built-in playlists.  These are the values
+// written by recent versions of iTunes; other values are used by older versions.
+const (
+	DistinguishedMovies      = 2
+	DistinguishedTVShows     = 3
+	DistinguishedMusic       = 4
+	DistinguishedAudiobooks  = 5
+	DistinguishedPodcasts    = 10
+	DistinguishedPurchased   = 19
+	DistinguishedITunesDJ    = 22
+	DistinguishedGenius      = 26
+	DistinguishedITunesU     = 31
+	DistinguishedMusicVideos = 47
+	DistinguishedHomeVideos  = 48
+	DistinguishedDownloaded  = 65
+)
+
+// MasterPlaylist returns the master playlist of the library (usually named "Library"), which
+// contains every track, and true if there is one.
+func (l Library) MasterPlaylist() (Playlist, bool) {
+	for _, p := range l.Playlists {
+		if p.Master {
+			return p, true
+		}
+	}
+	return Playlist{}, false
+}
+
+// DistinguishedPlaylist returns the first built-in playlist with the given DistinguishedKind
+// (such as DistinguishedMusic), and true if there is one.
+func (l Library) DistinguishedPlaylist(kind int) (Playlist, bool) {
+	for _, p := range l.Playlists {
+		if kind != 0 && p.DistinguishedKind == kind {
+			return p, true
+		}
+	}
+	return Playlist{}, false
+}
@@ -0,0 +1,57 @@
+// Copyright 2014, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package itl
+
+import "sort"
+
+// PodcastSeries returns the name of the podcast of the track: Series if it is set, otherwise
+// Album, which is where iTunes stores the podcast name.  Returns "" for tracks which aren't
+// podcasts.
+func (t Track) PodcastSeries() string {
+	if !t.Podcast {
+		return ""
+	}
+	if t.Series != "" {
+		return t.Series
+	}
+	return t.Album
+}
+
+// PodcastEpisodes returns the episodes of the podcast with the given name (see
+// Track.PodcastSeries), in episode order, see Podcasts.
+func (l Library) PodcastEpisodes(series string) []Track {
+	episodes := l.FilterTracks(func(t Track) bool {
+		return t.Podcast && t.PodcastSeries() == series
+	})
+	sortEpisodes(episodes)
+	return episodes
+}
+
+// Podcasts returns the podcast episodes in the library grouped by podcast name (see
+// Track.PodcastSeries).  Each group is in episode order: by Season, then EpisodeOrder, then
+// ReleaseDate, and then TrackID.  Episodes without a podcast name are omitted.
+func (l Library) Podcasts() map[string][]Track {
+	m := l.groupTracks(Track.PodcastSeries)
+	delete(m, "")
+	for _, episodes := range m {
+		sortEpisodes(episodes)
+	}
+	return m
+}
+
+// sortEpisodes sorts the podcast episodes, which are in ascending TrackID order, into
+// episode order.
+func sortEpisodes(episodes []Track) {
+	sort.SliceStable(episodes, func(i, j int) bool {
+		a, b := episodes[i], episodes[j]
+		if a.Season != b.Season {
+			return a.Season < b.Season
+		}
+		if a.EpisodeOrder != b.EpisodeOrder {
+			return a.EpisodeOrder < b.EpisodeOrder
+		}
+		return a.ReleaseDate.Before(b.ReleaseDate)
+	})
+}
@@ -0,0 +1,77 @@
+// Copyright 2014, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package itl
+
+import "strings"
+
+// AudioQuality is a rough classification of the audio quality of a track, see Track.Quality.
+type AudioQuality int
+
+// Audio qualities returned by Track.Quality.
+const (
+	QualityUnknown AudioQuality = iota
+	QualityLow
+	QualityMedium
+	QualityHigh
+	QualityLossless
+)
+
+var audioQualityNames = [...]string{
+	QualityUnknown:  "Unknown",
+	QualityLow:      "Low",
+	QualityMedium:   "Medium",
+	QualityHigh:     "High",
+	QualityLossless: "Lossless",
+}
+
+func (q AudioQuality) String() string {
+	if q >= 0 && int(q) < len(audioQualityNames) {
+		return audioQualityNames[q]
+	}
+	return "Unknown"
+}
+
+// Bit rate thresholds (in kbit/s) used by Track.Quality.
+const (
+	MediumBitRate = 128
+	HighBitRate   = 256
+)
+
+// LowSampleRate is the sample rate (in Hz) below which Track.Quality considers a
+// track to be QualityLow regardless of its bit rate.
+const LowSampleRate = 32000
+
+// Quality classifies the audio quality of the track:
+//
+//	QualityLossless  Kind is a lossless format (Apple Lossless, AIFF, WAV or FLAC),
+//	                 regardless of bit rate, which is variable for lossless files
+//	QualityUnknown   BitRate is not set
+//	QualityLow       BitRate is below MediumBitRate, or SampleRate is set and below LowSampleRate
+//	QualityMedium    BitRate is below HighBitRate
+//	QualityHigh      otherwise
+func (t Track) Quality() AudioQuality {
+	switch {
+	case isLosslessKind(t.Kind):
+		return QualityLossless
+	case t.BitRate == 0:
+		return QualityUnknown
+	case t.BitRate < MediumBitRate, t.SampleRate != 0 && t.SampleRate < LowSampleRate:
+		return QualityLow
+	case t.BitRate < HighBitRate:
+		return QualityMedium
+	}
+	return QualityHigh
+}
+
+// isLosslessKind returns true if the Kind string k describes a lossless audio format.
+func isLosslessKind(k string) bool {
+	k = englishKind(k)
+	for _, s := range []string{"lossless", "aiff", "wav", "flac"} {
+		if strings.Contains(k, s) {
+			return true
+		}
+	}
+	return false
+}
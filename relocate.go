@@ -0,0 +1,130 @@
+// Copyright 2014, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package itl
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Relocate rewrites the Location of every track whose local path (see LocalPath) is
+// oldPrefix, or is in the directory oldPrefix, so that it uses newPrefix instead.  MusicFolder
+// is updated in the same way.  Prefixes are compared with decoded paths ("/Volumes/Old Disk",
+// not "/Volumes/Old%20Disk") and must match whole path elements, so "/Music" doesn't match
+// "/Music2/x.mp3".  In rewritten locations newPrefix is encoded as a file URL and the rest
+// of the path is kept exactly as it was escaped (so a %2F in a file name stays a %2F);
+// locations which don't match are left exactly as they are.
+func (l *Library) Relocate(oldPrefix, newPrefix string) {
+	if loc, ok := relocateURL(l.MusicFolder, oldPrefix, newPrefix); ok {
+		l.MusicFolder = loc
+	}
+	for k, t := range l.Tracks {
+		if loc, ok := relocateURL(t.Location, oldPrefix, newPrefix); ok {
+			t.Location = loc
+			l.Tracks[k] = t
+		}
+	}
+}
+
+// TracksUnderPath returns the tracks whose local path (see LocalPath) is prefix or is inside
+// the directory prefix, in ascending TrackID order.  As with Relocate, prefix is a decoded
+// path and must match whole path elements; a trailing separator is optional.  Tracks which
+// aren't local files (such as streams) or have no Location are never returned.
+func (l Library) TracksUnderPath(prefix string) []Track {
+	return l.FilterTracks(func(t Track) bool {
+		p, err := t.LocalPath()
+		return err == nil && hasPathPrefix(p, prefix)
+	})
+}
+
+// relocateURL returns the file URL loc with the path prefix oldPrefix replaced by newPrefix,
+// and true if loc was changed.
+func relocateURL(loc, oldPrefix, newPrefix string) (string, bool) {
+	u, err := url.Parse(loc)
+	if err != nil || u.Scheme != "file" {
+		return "", false
+	}
+	p := urlLocalPath(u)
+	if !hasPathPrefix(p, oldPrefix) {
+		return "", false
+	}
+
+	// Keep the rest of the path as it was escaped in loc, so that escapes such as %2F
+	// (which can't be written as a path separator) are unchanged.
+	tail := escapedSuffix(u.EscapedPath(), len(p)-len(oldPrefix))
+	setURLLocalPath(u, newPrefix)
+	head := u.EscapedPath()
+	switch {
+	case strings.HasSuffix(head, "/") && strings.HasPrefix(tail, "/"):
+		head = head[:len(head)-1]
+	case tail != "" && !strings.HasSuffix(head, "/") && !strings.HasPrefix(tail, "/"):
+		head += "/"
+	}
+	u.RawPath = head + tail
+	if u.Path, err = url.PathUnescape(u.RawPath); err != nil {
+		return "", false
+	}
+	return u.String(), true
+}
+
+// escapedSuffix returns the suffix of the escaped URL path s which decodes to n bytes.
+func escapedSuffix(s string, n int) string {
+	var starts []int
+	for i := 0; i < len(s); i++ {
+		starts = append(starts, i)
+		if s[i] == '%' {
+			i += 2
+		}
+	}
+	if n > len(starts) {
+		return s
+	}
+	if n <= 0 {
+		return ""
+	}
+	return s[starts[len(starts)-n]:]
+}
+
+// hasPathPrefix returns true if prefix is p, or a directory containing p.
+func hasPathPrefix(p, prefix string) bool {
+	if prefix == "" || !strings.HasPrefix(p, prefix) {
+		return false
+	}
+	if len(p) == len(prefix) || isPathSeparator(prefix[len(prefix)-1]) {
+		return true
+	}
+	return isPathSeparator(p[len(prefix)])
+}
+
+func isPathSeparator(c byte) bool {
+	return c == '/' || c == '\\'
+}
+
+// setURLLocalPath sets the host and path of the file URL u from the filesystem path p,
+// reversing urlLocalPath.
+func setURLLocalPath(u *url.URL, p string) {
+	u.RawPath = ""
+	switch {
+	case strings.HasPrefix(p, `\\`):
+		p = strings.Replace(p[2:], `\`, "/", -1)
+		if i := strings.Index(p, "/"); i >= 0 {
+			u.Host, u.Path = p[:i], p[i:]
+			return
+		}
+		u.Host, u.Path = p, "/"
+
+	case len(p) >= 2 && p[1] == ':':
+		if u.Host != "" && u.Host != "localhost" {
+			u.Host = "localhost"
+		}
+		u.Path = "/" + strings.Replace(p, `\`, "/", -1)
+
+	default:
+		if u.Host != "" && u.Host != "localhost" {
+			u.Host = "localhost"
+		}
+		u.Path = p
+	}
+}
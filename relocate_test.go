@@ -0,0 +1,151 @@
+// Copyright 2014, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package itl
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRelocate(t *testing.T) {
+	tests := []struct {
+		name     string
+		loc      string
+		old, new string
+		want     string
+	}{
+		{
+			name: "spaces",
+			loc:  "file:///Volumes/Old%20Disk/Music/A%20B.mp3",
+			old:  "/Volumes/Old Disk",
+			new:  "/Volumes/New Disk",
+			want: "file:///Volumes/New%20Disk/Music/A%20B.mp3",
+		},
+		{
+			name: "escapes in tail",
+			loc:  "file:///Users/d/Music/%23%25&.mp3",
+			old:  "/Users/d/Music",
+			new:  "/Music",
+			want: "file:///Music/%23%25&.mp3",
+		},
+		{
+			name: "escapes in new prefix",
+			loc:  "file:///Music/x.mp3",
+			old:  "/Music",
+			new:  "/R&B #1 100%",
+			want: "file:///R&B%20%231%20100%25/x.mp3",
+		},
+		{
+			name: "non-ASCII",
+			loc:  "file:///Music/Bj%C3%B6rk/J%C3%B3ga.mp3",
+			old:  "/Music/Björk",
+			new:  "/Música/Björk",
+			want: "file:///M%C3%BAsica/Bj%C3%B6rk/J%C3%B3ga.mp3",
+		},
+		{
+			name: "escaped slash",
+			loc:  "file:///Music/AC%2FDC/Back%20in%20Black.mp3",
+			old:  "/Music",
+			new:  "/Media",
+			want: "file:///Media/AC%2FDC/Back%20in%20Black.mp3",
+		},
+		{
+			name: "trailing separator",
+			loc:  "file:///Music/x.mp3",
+			old:  "/Music/",
+			new:  "/Media",
+			want: "file:///Media/x.mp3",
+		},
+		{
+			name: "whole path",
+			loc:  "file:///Music/x.mp3",
+			old:  "/Music/x.mp3",
+			new:  "/Media/y.mp3",
+			want: "file:///Media/y.mp3",
+		},
+		{
+			name: "windows",
+			loc:  "file://localhost/C:/Users/d/Music/a%20b.mp3",
+			old:  `C:\Users\d\Music`,
+			new:  `D:\Music`,
+			want: "file://localhost/D:/Music/a%20b.mp3",
+		},
+		{
+			name: "unc",
+			loc:  "file://server/share/Music/a%2Fb.mp3",
+			old:  `\\server\share`,
+			new:  `\\nas\media`,
+			want: "file://nas/media/Music/a%2Fb.mp3",
+		},
+		{
+			name: "partial element",
+			loc:  "file:///Music2/x.mp3",
+			old:  "/Music",
+			new:  "/Media",
+			want: "file:///Music2/x.mp3",
+		},
+		{
+			name: "stream",
+			loc:  "http://example.com/Music/stream.mp3",
+			old:  "/Music",
+			new:  "/Media",
+			want: "http://example.com/Music/stream.mp3",
+		},
+	}
+
+	for _, tt := range tests {
+		l := Library{
+			MusicFolder: tt.loc,
+			Tracks:      map[string]Track{"1": {TrackID: 1, Location: tt.loc}},
+		}
+		l.Relocate(tt.old, tt.new)
+		if got := l.Tracks["1"].Location; got != tt.want {
+			t.Errorf("%s: Location = %q, want %q", tt.name, got, tt.want)
+		}
+		if l.MusicFolder != tt.want {
+			t.Errorf("%s: MusicFolder = %q, want %q", tt.name, l.MusicFolder, tt.want)
+		}
+	}
+}
+
+func TestTracksUnderPath(t *testing.T) {
+	l := Library{Tracks: map[string]Track{
+		"1": {TrackID: 1, Location: "file:///Volumes/Old%20Disk/Music/A%20B.mp3"},
+		"2": {TrackID: 2, Location: "file:///Music/Bj%C3%B6rk/J%C3%B3ga.mp3"},
+		"3": {TrackID: 3, Location: "file:///Music/AC%2FDC/%23%25&.mp3"},
+		"4": {TrackID: 4, Location: "file://localhost/C:/Users/d/Music/a%20b.mp3"},
+		"5": {TrackID: 5, Location: "file://server/share/Music/x.mp3"},
+		"6": {TrackID: 6, Location: "http://example.com/Music/stream.mp3"},
+		"7": {TrackID: 7},
+	}}
+
+	tests := []struct {
+		prefix string
+		want   []int
+	}{
+		{"/Volumes/Old Disk", []int{1}},
+		{"/Volumes/Old%20Disk", nil},
+		{"/Music", []int{2, 3}},
+		{"/Music/", []int{2, 3}},
+		{"/Music/Björk", []int{2}},
+		{"/Music/Bj", nil},
+		{"/Music/AC/DC/#%&.mp3", []int{3}},
+		{`C:\Users`, []int{4}},
+		{`C:\Users\d\Music\a b.mp3`, []int{4}},
+		{`\\server\share`, []int{5}},
+		{`\\server\sh`, nil},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		var got []int
+		for _, tr := range l.TracksUnderPath(tt.prefix) {
+			got = append(got, tr.TrackID)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("TracksUnderPath(%q) = %v, want %v", tt.prefix, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2014, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package itl
+
+import (
+	"sort"
+	"strings"
+)
+
+// searchField is a track field searched by SearchTracks, with the weight given to a match.
+type searchField struct {
+	value  func(Track) string
+	weight int
+}
+
+var searchFields = []searchField{
+	{func(t Track) string { return t.Name }, 4},
+	{func(t Track) string { return t.Artist }, 3},
+	{func(t Track) string { return t.AlbumArtist }, 3},
+	{func(t Track) string { return t.Album }, 2},
+	{func(t Track) string { return t.Composer }, 1},
+	{func(t Track) string { return t.Comments }, 1},
+	{func(t Track) string { return t.Grouping }, 1},
+}
+
+// SearchTracks returns the tracks which match query.  The query is split into words, and
+// a track matches if each word is a case-insensitive substring of at least one of its Name,
+// Artist, AlbumArtist, Album, Composer, Comments or Grouping.  Results are ordered by relevance:
+// each word scores for the best field it matches, with name matches ranked above artist
+// matches, which are ranked above album matches and then the remaining fields.  Tracks with
+// the same score are ordered by TrackID.
+func (l Library) SearchTracks(query string) []Track {
+	terms := strings.Fields(strings.ToLower(query))
+	if len(terms) == 0 {
+		return nil
+	}
+
+	type result struct {
+		t     Track
+		score int
+	}
+	var results []result
+	values := make([]string, len(searchFields))
+next:
+	for _, t := range l.sortedTracks() {
+		for i, f := range searchFields {
+			values[i] = strings.ToLower(f.value(t))
+		}
+		score := 0
+		for _, term := range terms {
+			best := 0
+			for i, f := range searchFields {
+				if f.weight > best && strings.Contains(values[i], term) {
+					best = f.weight
+				}
+			}
+			if best == 0 {
+				continue next
+			}
+			score += best
+		}
+		results = append(results, result{t, score})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].score > results[j].score
+	})
+	tracks := make([]Track, len(results))
+	for i, r := range results {
+		tracks[i] = r.t
+	}
+	return tracks
+}
+
+// TracksWithGrouping returns the tracks whose Grouping is g, ignoring case, in ascending
+// TrackID order.
+func (l Library) TracksWithGrouping(g string) []Track {
+	return l.FilterTracks(func(t Track) bool {
+		return strings.EqualFold(t.Grouping, g)
+	})
+}
+
+// TracksWithGroupingContaining returns the tracks whose Grouping contains s, ignoring case,
+// in ascending TrackID order.
+func (l Library) TracksWithGroupingContaining(s string) []Track {
+	s = strings.ToLower(s)
+	return l.FilterTracks(func(t Track) bool {
+		return strings.Contains(strings.ToLower(t.Grouping), s)
+	})
+}
@@ -0,0 +1,269 @@
+// Copyright 2014, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package itl
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+	"unicode/utf16"
+)
+
+// The Smart Criteria format has not been published by Apple, and the layout used here is
+// the commonly reverse-engineered one:
+//
+//	header (136 bytes):
+//	  0-3    "SLst"
+//	  15     1 if any rule can match, 0 if all rules must match
+//	rule (starting at byte 136, and continuing until the end of the data):
+//	  0-3    field (big-endian uint32)
+//	  4      sign: bit 0 set for string operands, bit 1 set for negated rules
+//	  6-7    operator (big-endian uint16)
+//	  52-55  length of the operand data (big-endian uint32)
+//	  56-    operand data
+//
+// String operands are UTF-16 (big-endian).  Integer and date operands are a 68 byte block
+// of big-endian values: the first operand at 0-7, the "in the last" count at 8-15, the
+// "in the last" unit (in seconds) at 16-23 and the second operand (for ranges) at 24-31.
+// Date operands are seconds since the Mac epoch (1904-01-01 UTC).
+const (
+	smartHeaderSize  = 136
+	smartMatchAnyOff = 15
+	smartRuleSize    = 56
+)
+
+var smartMagic = []byte("SLst")
+
+// ErrInvalidSmartCriteria is returned by ParseSmartCriteria when the data is not a
+// well-formed Smart Criteria blob.
+var ErrInvalidSmartCriteria = errors.New("itl: invalid smart criteria")
+
+// macEpoch is the zero time of the timestamps used by classic Mac OS (and iTunes).
+var macEpoch = time.Date(1904, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// SmartField is the track field tested by a SmartRule.
+type SmartField uint32
+
+// Fields which can be used in smart playlist rules.
+const (
+	FieldName         SmartField = 0x02
+	FieldAlbum        SmartField = 0x03
+	FieldArtist       SmartField = 0x04
+	FieldBitRate      SmartField = 0x05
+	FieldSampleRate   SmartField = 0x06
+	FieldYear         SmartField = 0x07
+	FieldGenre        SmartField = 0x08
+	FieldKind         SmartField = 0x09
+	FieldDateModified SmartField = 0x0a
+	FieldTrackNumber  SmartField = 0x0b
+	FieldSize         SmartField = 0x0c
+	FieldTime         SmartField = 0x0d
+	FieldComments     SmartField = 0x0e
+	FieldDateAdded    SmartField = 0x10
+	FieldComposer     SmartField = 0x12
+	FieldPlayCount    SmartField = 0x16
+	FieldLastPlayed   SmartField = 0x17
+	FieldDiscNumber   SmartField = 0x18
+	FieldRating       SmartField = 0x19
+	FieldCompilation  SmartField = 0x1f
+	FieldBPM          SmartField = 0x23
+	FieldGrouping     SmartField = 0x27
+	FieldPlaylist     SmartField = 0x28
+	FieldSkipCount    SmartField = 0x44
+	FieldLastSkipped  SmartField = 0x45
+	FieldAlbumArtist  SmartField = 0x47
+)
+
+var smartFieldNames = map[SmartField]string{
+	FieldName:         "Name",
+	FieldAlbum:        "Album",
+	FieldArtist:       "Artist",
+	FieldBitRate:      "Bit Rate",
+	FieldSampleRate:   "Sample Rate",
+	FieldYear:         "Year",
+	FieldGenre:        "Genre",
+	FieldKind:         "Kind",
+	FieldDateModified: "Date Modified",
+	FieldTrackNumber:  "Track Number",
+	FieldSize:         "Size",
+	FieldTime:         "Time",
+	FieldComments:     "Comments",
+	FieldDateAdded:    "Date Added",
+	FieldComposer:     "Composer",
+	FieldPlayCount:    "Play Count",
+	FieldLastPlayed:   "Last Played",
+	FieldDiscNumber:   "Disc Number",
+	FieldRating:       "Rating",
+	FieldCompilation:  "Compilation",
+	FieldBPM:          "BPM",
+	FieldGrouping:     "Grouping",
+	FieldPlaylist:     "Playlist",
+	FieldSkipCount:    "Skip Count",
+	FieldLastSkipped:  "Last Skipped",
+	FieldAlbumArtist:  "Album Artist",
+}
+
+func (f SmartField) String() string {
+	if s, ok := smartFieldNames[f]; ok {
+		return s
+	}
+	return fmt.Sprintf("SmartField(%#x)", uint32(f))
+}
+
+// isDate returns true if the operands of rules on f are dates.
+func (f SmartField) isDate() bool {
+	switch f {
+	case FieldDateModified, FieldDateAdded, FieldLastPlayed, FieldLastSkipped:
+		return true
+	}
+	return false
+}
+
+// SmartOperator is the comparison made by a SmartRule.
+type SmartOperator uint16
+
+// Operators used in smart playlist rules.
+const (
+	OpIs          SmartOperator = 0x0001
+	OpContains    SmartOperator = 0x0002
+	OpStartsWith  SmartOperator = 0x0004
+	OpEndsWith    SmartOperator = 0x0008
+	OpGreaterThan SmartOperator = 0x0010
+	OpLessThan    SmartOperator = 0x0040
+	OpInRange     SmartOperator = 0x0100
+	OpInTheLast   SmartOperator = 0x0200
+)
+
+var smartOperatorNames = map[SmartOperator]string{
+	OpIs:          "is",
+	OpContains:    "contains",
+	OpStartsWith:  "starts with",
+	OpEndsWith:    "ends with",
+	OpGreaterThan: "is greater than",
+	OpLessThan:    "is less than",
+	OpInRange:     "is in the range",
+	OpInTheLast:   "is in the last",
+}
+
+func (o SmartOperator) String() string {
+	if s, ok := smartOperatorNames[o]; ok {
+		return s
+	}
+	return fmt.Sprintf("SmartOperator(%#x)", uint16(o))
+}
+
+// SmartCriteria is the decoded set of rules of a smart playlist.
+type SmartCriteria struct {
+	// MatchAll is true if a track must match all of the rules, and false if
+	// matching any one of them is enough.
+	MatchAll bool
+
+	// LiveUpdating is true if iTunes updates the playlist as the library changes.
+	// It is stored in the playlist Smart Info, and so is only set by Playlist.Smart.
+	LiveUpdating bool
+
+	Rules []SmartRule
+}
+
+// SmartRule is an individual condition in a smart playlist.
+type SmartRule struct {
+	Field    SmartField
+	Operator SmartOperator
+	Negated  bool
+
+	// String is the operand of rules on string fields.
+	String string
+
+	// Int and IntB are the operands of rules on integer fields.  IntB is only used
+	// by OpInRange.
+	Int, IntB int64
+
+	// Time and TimeB are the operands of rules on date fields.  TimeB is only used
+	// by OpInRange.
+	Time, TimeB time.Time
+
+	// Last is the duration for OpInTheLast rules.
+	Last time.Duration
+}
+
+// ParseSmartCriteria decodes the Smart Criteria data of a smart playlist.
+func ParseSmartCriteria(b []byte) (*SmartCriteria, error) {
+	if len(b) < smartHeaderSize || !bytes.Equal(b[:len(smartMagic)], smartMagic) {
+		return nil, ErrInvalidSmartCriteria
+	}
+
+	c := &SmartCriteria{
+		MatchAll: b[smartMatchAnyOff] == 0,
+	}
+	for off := smartHeaderSize; off < len(b); {
+		if len(b)-off < smartRuleSize {
+			return nil, ErrInvalidSmartCriteria
+		}
+		rule := b[off:]
+		n := int(binary.BigEndian.Uint32(rule[52:56]))
+		if n < 0 || n > len(rule)-smartRuleSize {
+			return nil, ErrInvalidSmartCriteria
+		}
+		data := rule[smartRuleSize : smartRuleSize+n]
+
+		r := SmartRule{
+			Field:    SmartField(binary.BigEndian.Uint32(rule[0:4])),
+			Operator: SmartOperator(binary.BigEndian.Uint16(rule[6:8])),
+			Negated:  rule[4]&0x02 != 0,
+		}
+		if rule[4]&0x01 != 0 {
+			r.String = decodeUTF16BE(data)
+		} else {
+			if len(data) < 32 {
+				return nil, ErrInvalidSmartCriteria
+			}
+			a := int64(binary.BigEndian.Uint64(data[0:8]))
+			count := int64(binary.BigEndian.Uint64(data[8:16]))
+			unit := int64(binary.BigEndian.Uint64(data[16:24]))
+			bb := int64(binary.BigEndian.Uint64(data[24:32]))
+
+			switch {
+			case r.Operator == OpInTheLast:
+				if count < 0 {
+					count = -count
+				}
+				r.Last = time.Duration(count*unit) * time.Second
+			case r.Field.isDate():
+				r.Time = macEpoch.Add(time.Duration(a) * time.Second)
+				r.TimeB = macEpoch.Add(time.Duration(bb) * time.Second)
+			default:
+				r.Int, r.IntB = a, bb
+			}
+		}
+		c.Rules = append(c.Rules, r)
+		off += smartRuleSize + n
+	}
+	return c, nil
+}
+
+// Smart decodes the rules of the smart playlist p, returning nil if p has no
+// Smart Criteria.
+func (p Playlist) Smart() (*SmartCriteria, error) {
+	if len(p.SmartCriteria) == 0 {
+		return nil, nil
+	}
+	c, err := ParseSmartCriteria(p.SmartCriteria)
+	if err != nil {
+		return nil, err
+	}
+	c.LiveUpdating = len(p.SmartInfo) > 0 && p.SmartInfo[0] == 1
+	return c, nil
+}
+
+// decodeUTF16BE decodes big-endian UTF-16 data.
+func decodeUTF16BE(b []byte) string {
+	u := make([]uint16, len(b)/2)
+	for i := range u {
+		u[i] = binary.BigEndian.Uint16(b[2*i:])
+	}
+	return string(utf16.Decode(u))
+}
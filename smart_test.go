@@ -0,0 +1,207 @@
+// Copyright 2014, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package itl
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+	"time"
+	"unicode/utf16"
+)
+
+// smartBlob returns Smart Criteria data with the given match-any flag and rules.
+func smartBlob(matchAny bool, rules ...[]byte) []byte {
+	b := make([]byte, smartHeaderSize)
+	copy(b, smartMagic)
+	if matchAny {
+		b[smartMatchAnyOff] = 1
+	}
+	for _, r := range rules {
+		b = append(b, r...)
+	}
+	return b
+}
+
+// smartRule returns a rule with the given field, sign byte, operator and operand data.
+func smartRule(f SmartField, sign byte, op SmartOperator, data []byte) []byte {
+	r := make([]byte, smartRuleSize, smartRuleSize+len(data))
+	binary.BigEndian.PutUint32(r[0:], uint32(f))
+	r[4] = sign
+	binary.BigEndian.PutUint16(r[6:], uint16(op))
+	binary.BigEndian.PutUint32(r[52:], uint32(len(data)))
+	return append(r, data...)
+}
+
+// smartString returns s as the operand data of a string rule.
+func smartString(s string) []byte {
+	u := utf16.Encode([]rune(s))
+	b := make([]byte, 2*len(u))
+	for i, r := range u {
+		binary.BigEndian.PutUint16(b[2*i:], r)
+	}
+	return b
+}
+
+// smartInts returns the 68 byte operand data of an integer or date rule.
+func smartInts(a, count, unit, b int64) []byte {
+	d := make([]byte, 68)
+	binary.BigEndian.PutUint64(d[0:], uint64(a))
+	binary.BigEndian.PutUint64(d[8:], uint64(count))
+	binary.BigEndian.PutUint64(d[16:], uint64(unit))
+	binary.BigEndian.PutUint64(d[24:], uint64(b))
+	return d
+}
+
+// macSeconds returns t as seconds since the Mac epoch.
+func macSeconds(t time.Time) int64 {
+	return int64(t.Sub(macEpoch) / time.Second)
+}
+
+func TestParseSmartCriteria(t *testing.T) {
+	date := time.Date(2014, time.March, 1, 12, 0, 0, 0, time.UTC)
+	dateB := time.Date(2014, time.June, 30, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		in   []byte
+		want *SmartCriteria
+	}{
+		{
+			name: "no rules",
+			in:   smartBlob(false),
+			want: &SmartCriteria{MatchAll: true},
+		},
+		{
+			name: "string",
+			in:   smartBlob(false, smartRule(FieldArtist, 0x01, OpContains, smartString("Björk"))),
+			want: &SmartCriteria{
+				MatchAll: true,
+				Rules:    []SmartRule{{Field: FieldArtist, Operator: OpContains, String: "Björk"}},
+			},
+		},
+		{
+			name: "negated string",
+			in:   smartBlob(false, smartRule(FieldGenre, 0x03, OpIs, smartString("Podcast"))),
+			want: &SmartCriteria{
+				MatchAll: true,
+				Rules:    []SmartRule{{Field: FieldGenre, Operator: OpIs, Negated: true, String: "Podcast"}},
+			},
+		},
+		{
+			name: "integer",
+			in:   smartBlob(false, smartRule(FieldPlayCount, 0, OpGreaterThan, smartInts(10, 0, 0, 10))),
+			want: &SmartCriteria{
+				MatchAll: true,
+				Rules:    []SmartRule{{Field: FieldPlayCount, Operator: OpGreaterThan, Int: 10, IntB: 10}},
+			},
+		},
+		{
+			name: "integer range",
+			in:   smartBlob(false, smartRule(FieldYear, 0, OpInRange, smartInts(1990, 0, 0, 1999))),
+			want: &SmartCriteria{
+				MatchAll: true,
+				Rules:    []SmartRule{{Field: FieldYear, Operator: OpInRange, Int: 1990, IntB: 1999}},
+			},
+		},
+		{
+			name: "date range",
+			in:   smartBlob(false, smartRule(FieldDateAdded, 0, OpInRange, smartInts(macSeconds(date), 0, 0, macSeconds(dateB)))),
+			want: &SmartCriteria{
+				MatchAll: true,
+				Rules:    []SmartRule{{Field: FieldDateAdded, Operator: OpInRange, Time: date, TimeB: dateB}},
+			},
+		},
+		{
+			name: "in the last 14 days",
+			in:   smartBlob(false, smartRule(FieldLastPlayed, 0, OpInTheLast, smartInts(0, -14, 86400, 0))),
+			want: &SmartCriteria{
+				MatchAll: true,
+				Rules:    []SmartRule{{Field: FieldLastPlayed, Operator: OpInTheLast, Last: 14 * 24 * time.Hour}},
+			},
+		},
+		{
+			name: "match any",
+			in: smartBlob(true,
+				smartRule(FieldAlbum, 0x01, OpStartsWith, smartString("Greatest")),
+				smartRule(FieldRating, 0, OpIs, smartInts(100, 0, 0, 100)),
+			),
+			want: &SmartCriteria{
+				MatchAll: false,
+				Rules: []SmartRule{
+					{Field: FieldAlbum, Operator: OpStartsWith, String: "Greatest"},
+					{Field: FieldRating, Operator: OpIs, Int: 100, IntB: 100},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseSmartCriteria(tt.in)
+		if err != nil {
+			t.Errorf("%s: ParseSmartCriteria() error = %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: ParseSmartCriteria() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseSmartCriteriaErrors(t *testing.T) {
+	valid := smartBlob(false, smartRule(FieldPlayCount, 0, OpIs, smartInts(1, 0, 0, 1)))
+	badMagic := append([]byte(nil), valid...)
+	copy(badMagic, "XLst")
+
+	tests := []struct {
+		name string
+		in   []byte
+	}{
+		{"empty", nil},
+		{"short header", valid[:smartHeaderSize-1]},
+		{"magic", badMagic},
+		{"short rule", valid[:smartHeaderSize+smartRuleSize-1]},
+		{"short operand", valid[:len(valid)-1]},
+		{"short integer operand", smartBlob(false, smartRule(FieldPlayCount, 0, OpIs, make([]byte, 31)))},
+	}
+
+	for _, tt := range tests {
+		if _, err := ParseSmartCriteria(tt.in); err != ErrInvalidSmartCriteria {
+			t.Errorf("%s: ParseSmartCriteria() error = %v, want ErrInvalidSmartCriteria", tt.name, err)
+		}
+	}
+}
+
+func TestPlaylistSmart(t *testing.T) {
+	criteria := smartBlob(false, smartRule(FieldName, 0x01, OpIs, smartString("x")))
+
+	tests := []struct {
+		name     string
+		playlist Playlist
+		live     bool
+	}{
+		{"live", Playlist{SmartInfo: []byte{1, 0, 0, 0}, SmartCriteria: criteria}, true},
+		{"not live", Playlist{SmartInfo: []byte{0, 0, 0, 0}, SmartCriteria: criteria}, false},
+		{"no smart info", Playlist{SmartCriteria: criteria}, false},
+	}
+
+	for _, tt := range tests {
+		c, err := tt.playlist.Smart()
+		if err != nil {
+			t.Errorf("%s: Smart() error = %v", tt.name, err)
+			continue
+		}
+		if c.LiveUpdating != tt.live {
+			t.Errorf("%s: LiveUpdating = %v, want %v", tt.name, c.LiveUpdating, tt.live)
+		}
+	}
+
+	if c, err := (Playlist{}).Smart(); c != nil || err != nil {
+		t.Errorf("Playlist{}.Smart() = %v, %v, want nil, nil", c, err)
+	}
+	if _, err := (Playlist{SmartCriteria: criteria[:10]}).Smart(); err != ErrInvalidSmartCriteria {
+		t.Errorf("Smart() with truncated criteria error = %v, want ErrInvalidSmartCriteria", err)
+	}
+}
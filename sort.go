@@ -0,0 +1,92 @@
+// Copyright 2014, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package itl
+
+import (
+	"sort"
+	"strings"
+)
+
+// TrackSlice is a slice of tracks which can be sorted using ByName, ByArtist,
+// ByDateAdded and ByPlayCount, for example:
+//
+//	tracks := l.TrackList()
+//	sort.Sort(tracks.ByArtist())
+type TrackSlice []Track
+
+// TrackList returns the tracks in the library as a TrackSlice in ascending TrackID order.
+func (l Library) TrackList() TrackSlice {
+	return TrackSlice(l.sortedTracks())
+}
+
+// trackSorter implements sort.Interface for a TrackSlice using the given less function.
+// Ties are broken by TrackID so that the ordering is deterministic.
+type trackSorter struct {
+	TrackSlice
+	less func(a, b Track) bool
+}
+
+func (s trackSorter) Len() int { return len(s.TrackSlice) }
+func (s trackSorter) Swap(i, j int) {
+	s.TrackSlice[i], s.TrackSlice[j] = s.TrackSlice[j], s.TrackSlice[i]
+}
+
+func (s trackSorter) Less(i, j int) bool {
+	a, b := s.TrackSlice[i], s.TrackSlice[j]
+	if s.less(a, b) {
+		return true
+	}
+	if s.less(b, a) {
+		return false
+	}
+	return a.TrackID < b.TrackID
+}
+
+// ByName returns a sort.Interface which orders tracks by name, using SortName when set
+// (as iTunes does).  Comparison is case-insensitive.
+func (s TrackSlice) ByName() sort.Interface {
+	return trackSorter{s, func(a, b Track) bool {
+		return compareSortKeys(a.SortName, a.Name, b.SortName, b.Name) < 0
+	}}
+}
+
+// ByArtist returns a sort.Interface which orders tracks by artist, using SortArtist when
+// set (as iTunes does), and then by name.  Comparison is case-insensitive.
+func (s TrackSlice) ByArtist() sort.Interface {
+	return trackSorter{s, func(a, b Track) bool {
+		if c := compareSortKeys(a.SortArtist, a.Artist, b.SortArtist, b.Artist); c != 0 {
+			return c < 0
+		}
+		return compareSortKeys(a.SortName, a.Name, b.SortName, b.Name) < 0
+	}}
+}
+
+// ByDateAdded returns a sort.Interface which orders tracks by the date they were added
+// to the library, oldest first.
+func (s TrackSlice) ByDateAdded() sort.Interface {
+	return trackSorter{s, func(a, b Track) bool {
+		return a.DateAdded.Before(b.DateAdded)
+	}}
+}
+
+// ByPlayCount returns a sort.Interface which orders tracks by play count, least played
+// first.  Use sort.Reverse for most played first.
+func (s TrackSlice) ByPlayCount() sort.Interface {
+	return trackSorter{s, func(a, b Track) bool {
+		return a.PlayCount < b.PlayCount
+	}}
+}
+
+// compareSortKeys compares two values using their sort variants when set, and otherwise
+// the values themselves.
+func compareSortKeys(sortA, a, sortB, b string) int {
+	if sortA == "" {
+		sortA = a
+	}
+	if sortB == "" {
+		sortB = b
+	}
+	return strings.Compare(strings.ToLower(sortA), strings.ToLower(sortB))
+}
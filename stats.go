@@ -0,0 +1,199 @@
+// Copyright 2014, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package itl
+
+import "time"
+
+// LibraryStats holds aggregate statistics for a Library.
+type LibraryStats struct {
+	TrackCount int
+
+	// Size is the total size of all tracks in bytes.  It is summed as an int64 so
+	// does not overflow for large libraries on 32-bit platforms.
+	Size int64
+
+	// TotalTime is the total duration of all tracks.
+	TotalTime time.Duration
+
+	// Kinds is the number of tracks of each Kind.
+	Kinds map[string]int
+
+	Protected int
+	Purchased int
+	Podcasts  int
+	Movies    int
+
+	// Playlists is the number of playlists, excluding folders.
+	Playlists int
+}
+
+// Stats computes aggregate statistics for the library.
+func (l Library) Stats() LibraryStats {
+	s := LibraryStats{
+		TrackCount: len(l.Tracks),
+		Kinds:      make(map[string]int),
+	}
+	for _, t := range l.Tracks {
+		s.Size += int64(t.Size)
+		s.TotalTime += t.Duration()
+		s.Kinds[t.Kind]++
+		if t.Protected {
+			s.Protected++
+		}
+		if t.Purchased {
+			s.Purchased++
+		}
+		if t.Podcast {
+			s.Podcasts++
+		}
+		if t.Movie {
+			s.Movies++
+		}
+	}
+	for _, p := range l.Playlists {
+		if !p.Folder {
+			s.Playlists++
+		}
+	}
+	return s
+}
+
+// SizeByGenre returns the total Size in bytes of the tracks of each Genre.  Tracks without
+// a genre are counted under the empty string.  Tracks with no size (such as streams) are
+// counted as zero rather than skipped, so every genre in the library is present.
+func (l Library) SizeByGenre() map[string]int64 {
+	return l.sizeBy(func(t Track) string { return t.Genre })
+}
+
+// SizeByArtist returns the total Size in bytes of the tracks of each Artist, in the same
+// way as SizeByGenre.
+func (l Library) SizeByArtist() map[string]int64 {
+	return l.sizeBy(func(t Track) string { return t.Artist })
+}
+
+// sizeBy returns the total Size of the tracks in the library grouped by key.
+func (l Library) sizeBy(key func(Track) string) map[string]int64 {
+	m := make(map[string]int64)
+	for _, t := range l.Tracks {
+		m[key(t)] += int64(t.Size)
+	}
+	return m
+}
+
+// DateAddedRange returns the earliest and latest DateAdded of the tracks in the library.
+// Tracks without a DateAdded (those with the zero time) are ignored, so earliest is never
+// the zero time unless no track has a DateAdded, in which case both are zero.
+func (l Library) DateAddedRange() (earliest, latest time.Time) {
+	return l.dateRange(func(t Track) time.Time { return t.DateAdded })
+}
+
+// PlayDateRange returns the earliest and latest PlayDateUTC (the time each track was last
+// played) of the tracks in the library.  As with DateAddedRange, tracks without a
+// PlayDateUTC are ignored, and both times are zero if no track has one.
+func (l Library) PlayDateRange() (earliest, latest time.Time) {
+	return l.dateRange(func(t Track) time.Time { return t.PlayDateUTC })
+}
+
+// dateRange returns the earliest and latest non-zero date of the tracks in the library.
+func (l Library) dateRange(date func(Track) time.Time) (earliest, latest time.Time) {
+	for _, t := range l.Tracks {
+		d := date(t)
+		if d.IsZero() {
+			continue
+		}
+		if earliest.IsZero() || d.Before(earliest) {
+			earliest = d
+		}
+		if d.After(latest) {
+			latest = d
+		}
+	}
+	return earliest, latest
+}
+
+// PlaylistStats holds aggregate statistics for a playlist, see Library.PlaylistStats.
+type PlaylistStats struct {
+	// TrackCount is the number of items in the playlist which reference a track in
+	// the library.  Tracks in the playlist more than once are counted each time.
+	TrackCount int
+
+	// Missing is the number of items which reference a track not in the library.
+	Missing int
+
+	// Size is the total size of the tracks in bytes.
+	Size int64
+
+	// TotalTime is the total duration of the tracks.
+	TotalTime time.Duration
+}
+
+// PlaylistStats computes aggregate statistics for the tracks of p.  Items are resolved with
+// GetTrack, and those which reference missing tracks are counted in Missing rather than
+// contributing to the totals.
+func (l Library) PlaylistStats(p Playlist) PlaylistStats {
+	var s PlaylistStats
+	for _, item := range p.PlaylistItems {
+		t, ok := l.GetTrack(item.TrackID)
+		if !ok {
+			s.Missing++
+			continue
+		}
+		s.TrackCount++
+		s.Size += int64(t.Size)
+		s.TotalTime += t.Duration()
+	}
+	return s
+}
+
+// EstimatedListeningTime returns an estimate of the total time spent listening to the tracks
+// in the library: the sum of the play length of each track (its TotalTime, less any part
+// excluded by Start Time and Stop Time, see PlayTime) multiplied by its PlayCount.  It is an
+// estimate since iTunes only counts plays which reach the end of the track, and partial
+// plays aren't recorded.  The sum is computed in int64 milliseconds, so doesn't overflow on
+// 32-bit platforms.
+func (l Library) EstimatedListeningTime() time.Duration {
+	var ms int64
+	for _, t := range l.Tracks {
+		ms += listeningTime(t)
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+// EstimatedListeningTimeByGenre returns the EstimatedListeningTime of the tracks of each
+// Genre.  Tracks without a genre are counted under the empty string, and genres which
+// haven't been played are omitted.
+func (l Library) EstimatedListeningTimeByGenre() map[string]time.Duration {
+	return l.listeningTimeBy(func(t Track) string { return t.Genre })
+}
+
+// EstimatedListeningTimeByArtist returns the EstimatedListeningTime of the tracks of each
+// Artist, in the same way as EstimatedListeningTimeByGenre.
+func (l Library) EstimatedListeningTimeByArtist() map[string]time.Duration {
+	return l.listeningTimeBy(func(t Track) string { return t.Artist })
+}
+
+// listeningTimeBy returns the estimated listening time of the tracks in the library grouped
+// by key.
+func (l Library) listeningTimeBy(key func(Track) string) map[string]time.Duration {
+	ms := make(map[string]int64)
+	for _, t := range l.Tracks {
+		if n := listeningTime(t); n > 0 {
+			ms[key(t)] += n
+		}
+	}
+	m := make(map[string]time.Duration, len(ms))
+	for k, n := range ms {
+		m[k] = time.Duration(n) * time.Millisecond
+	}
+	return m
+}
+
+// listeningTime returns the estimated time spent listening to t in milliseconds.
+func listeningTime(t Track) int64 {
+	if t.PlayCount <= 0 || t.PlayTime() <= 0 {
+		return 0
+	}
+	return int64(t.PlayTime()) * int64(t.PlayCount)
+}
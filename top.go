@@ -0,0 +1,77 @@
+// Copyright 2014, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package itl
+
+import (
+	"container/heap"
+	"sort"
+)
+
+// TopPlayed returns the n tracks with the highest PlayCount, most played first.  Ties are
+// broken by PersistentID.  If n exceeds the number of tracks then all are returned.
+func (l Library) TopPlayed(n int) []Track {
+	return l.topTracks(n, func(a, b Track) bool {
+		if a.PlayCount != b.PlayCount {
+			return a.PlayCount > b.PlayCount
+		}
+		return tieBreak(a, b)
+	})
+}
+
+// TopRated returns the n tracks with the highest Rating, highest rated first.  Ties are
+// broken by PersistentID.  If n exceeds the number of tracks then all are returned.
+func (l Library) TopRated(n int) []Track {
+	return l.topTracks(n, func(a, b Track) bool {
+		if a.Rating != b.Rating {
+			return a.Rating > b.Rating
+		}
+		return tieBreak(a, b)
+	})
+}
+
+// tieBreak orders tracks by PersistentID, and then TrackID for tracks without one.
+func tieBreak(a, b Track) bool {
+	if a.PersistentID != b.PersistentID {
+		return a.PersistentID < b.PersistentID
+	}
+	return a.TrackID < b.TrackID
+}
+
+// topTracks returns the first n tracks in the ordering defined by better, using a heap
+// of size n so the whole library is not sorted.
+func (l Library) topTracks(n int, better func(a, b Track) bool) []Track {
+	if n <= 0 {
+		return nil
+	}
+	h := &trackHeap{worse: func(a, b Track) bool { return better(b, a) }}
+	for _, t := range l.Tracks {
+		switch {
+		case len(h.tracks) < n:
+			heap.Push(h, t)
+		case better(t, h.tracks[0]):
+			h.tracks[0] = t
+			heap.Fix(h, 0)
+		}
+	}
+	sort.Slice(h.tracks, func(i, j int) bool { return better(h.tracks[i], h.tracks[j]) })
+	return h.tracks
+}
+
+// trackHeap is a heap of tracks with the worst track at the root.
+type trackHeap struct {
+	tracks []Track
+	worse  func(a, b Track) bool
+}
+
+func (h *trackHeap) Len() int           { return len(h.tracks) }
+func (h *trackHeap) Less(i, j int) bool { return h.worse(h.tracks[i], h.tracks[j]) }
+func (h *trackHeap) Swap(i, j int)      { h.tracks[i], h.tracks[j] = h.tracks[j], h.tracks[i] }
+func (h *trackHeap) Push(x interface{}) { h.tracks = append(h.tracks, x.(Track)) }
+
+func (h *trackHeap) Pop() interface{} {
+	t := h.tracks[len(h.tracks)-1]
+	h.tracks = h.tracks[:len(h.tracks)-1]
+	return t
+}
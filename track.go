@@ -0,0 +1,135 @@
+// Copyright 2014, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package itl
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrNotLocal is returned by LocalPath when the track Location does not refer to a
+// local file (for instance an http streaming URL).
+var ErrNotLocal = errors.New("itl: track location is not a local file")
+
+// LocalPath returns the filesystem path of the file referenced by the track Location,
+// which is stored as a percent-encoded file URL.  Both file://localhost/... and file:///...
+// forms are supported.  Windows drive URLs such as file://localhost/C:/Music/x.mp3 are
+// returned as C:\Music\x.mp3, and URLs with another host as UNC paths (\\host\share\...).
+// Returns ErrNotLocal if the Location is not a file URL.
+func (t Track) LocalPath() (string, error) {
+	u, err := url.Parse(t.Location)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "file" {
+		return "", ErrNotLocal
+	}
+	return urlLocalPath(u), nil
+}
+
+// IsLocal returns true if the track is a local file: its Location is a file URL, or it has
+// no Location and its TrackType is "File" (the file is known to iTunes but its location
+// wasn't exported).  Tracks with no Location and another TrackType, such as Apple Music or
+// iTunes Match tracks in the cloud (TrackType "Remote"), are neither local nor streams.
+func (t Track) IsLocal() bool {
+	if t.Location == "" {
+		return t.TrackType == "File"
+	}
+	return locationScheme(t.Location) == "file"
+}
+
+// IsStream returns true if the Location of the track is an http or https URL, as for
+// internet radio streams.  See IsLocal for tracks without a Location.
+func (t Track) IsStream() bool {
+	s := locationScheme(t.Location)
+	return s == "http" || s == "https"
+}
+
+// locationScheme returns the (lower case) scheme of the URL loc, or "" if it can't be parsed.
+func locationScheme(loc string) string {
+	u, err := url.Parse(loc)
+	if err != nil {
+		return ""
+	}
+	return u.Scheme
+}
+
+// urlLocalPath returns the filesystem path of the file URL u, see LocalPath.
+func urlLocalPath(u *url.URL) string {
+	p := u.Path
+	if len(p) >= 3 && p[0] == '/' && p[2] == ':' {
+		return strings.Replace(p[1:], "/", `\`, -1)
+	}
+	if u.Host != "" && u.Host != "localhost" {
+		return `\\` + u.Host + strings.Replace(p, "/", `\`, -1)
+	}
+	return p
+}
+
+// PlayTime returns the effective play length of the track in milliseconds, taking
+// into account any custom Start Time and Stop Time.
+func (t Track) PlayTime() int {
+	stop := t.StopTime
+	if stop == 0 {
+		stop = t.TotalTime
+	}
+	return stop - t.StartTime
+}
+
+// VolumePercent returns the track Volume Adjustment as a percentage in the range
+// -100 to 100.  iTunes stores the adjustment as an integer in the range -255 to 255,
+// which this maps linearly: 0 leaves the volume unchanged, -255 (-100%) is silent and
+// 255 (+100%) is the maximum boost.  Out of range values are clamped.
+func (t Track) VolumePercent() float64 {
+	p := float64(t.VolumeAdjustment) * 100 / 255
+	if p > 100 {
+		return 100
+	}
+	if p < -100 {
+		return -100
+	}
+	return p
+}
+
+// Duration returns the TotalTime of the track as a time.Duration.
+func (t Track) Duration() time.Duration {
+	return time.Duration(t.TotalTime) * time.Millisecond
+}
+
+// PlayDateTime returns the legacy integer Play Date (seconds since the Mac epoch,
+// 1904-01-01) as a time.Time, or the zero time if it is not set.  iTunes writes Play Date
+// in the local time of the machine which exported the library, so it differs from
+// PlayDateUTC by that machine's UTC offset; prefer PlayDateUTC when it is set, as older
+// libraries only have Play Date.
+func (t Track) PlayDateTime() time.Time {
+	if t.PlayDate == 0 {
+		return time.Time{}
+	}
+	return macEpoch.Add(time.Duration(t.PlayDate) * time.Second)
+}
+
+// EffectiveSortSeries returns the value used to sort the track by series: SortSeries if
+// set, otherwise Series.
+func (t Track) EffectiveSortSeries() string {
+	if t.SortSeries != "" {
+		return t.SortSeries
+	}
+	return t.Series
+}
+
+// DisplayArtist returns the artist to display for the track.  For compilations this is
+// AlbumArtist, or VariousArtists if AlbumArtist isn't set, otherwise it is Artist (so
+// featured artists are kept).  SortArtist is not used: it only affects ordering.
+func (t Track) DisplayArtist() string {
+	if t.Compilation {
+		if t.AlbumArtist != "" {
+			return t.AlbumArtist
+		}
+		return VariousArtists
+	}
+	return t.Artist
+}
@@ -0,0 +1,42 @@
+// Copyright 2014, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package itl
+
+import "testing"
+
+func TestDisplayArtist(t *testing.T) {
+	tests := []struct {
+		name  string
+		track Track
+		want  string
+	}{
+		{
+			name:  "artist",
+			track: Track{Artist: "Led Zeppelin", SortArtist: "Zeppelin, Led"},
+			want:  "Led Zeppelin",
+		},
+		{
+			name:  "compilation with album artist",
+			track: Track{Artist: "David Bowie", AlbumArtist: "Various Artists Remixed", Compilation: true},
+			want:  "Various Artists Remixed",
+		},
+		{
+			name:  "compilation without album artist",
+			track: Track{Artist: "David Bowie", Compilation: true},
+			want:  VariousArtists,
+		},
+		{
+			name:  "featured artist",
+			track: Track{Artist: "Daft Punk feat. Pharrell Williams", AlbumArtist: "Daft Punk"},
+			want:  "Daft Punk feat. Pharrell Williams",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := tt.track.DisplayArtist(); got != tt.want {
+			t.Errorf("%s: DisplayArtist() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright 2014, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package itl
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// ValidationCode identifies the kind of problem described by a ValidationError.
+type ValidationCode int
+
+// Problems reported by Validate.
+const (
+	// MissingTrack is a PlaylistItem which references a TrackID which isn't in the library.
+	MissingTrack ValidationCode = iota + 1
+
+	// DuplicatePersistentID is a PersistentID shared by more than one track.
+	DuplicatePersistentID
+
+	// MissingParent is a playlist whose ParentPersistentID doesn't match a playlist in
+	// the library.
+	MissingParent
+
+	// NoMasterPlaylist is reported when the library has no master playlist.
+	NoMasterPlaylist
+
+	// MultipleMasterPlaylists is reported when the library has more than one master playlist.
+	MultipleMasterPlaylists
+
+	// ParentNotFolder is a playlist whose ParentPersistentID is a playlist which isn't a
+	// folder.
+	ParentNotFolder
+)
+
+var validationCodeNames = map[ValidationCode]string{
+	MissingTrack:            "MissingTrack",
+	DuplicatePersistentID:   "DuplicatePersistentID",
+	MissingParent:           "MissingParent",
+	NoMasterPlaylist:        "NoMasterPlaylist",
+	MultipleMasterPlaylists: "MultipleMasterPlaylists",
+	ParentNotFolder:         "ParentNotFolder",
+}
+
+func (c ValidationCode) String() string {
+	if s, ok := validationCodeNames[c]; ok {
+		return s
+	}
+	return fmt.Sprintf("ValidationCode(%d)", int(c))
+}
+
+// ValidationError is a structural problem in a Library reported by Validate.
+type ValidationError struct {
+	Code    ValidationCode
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	return "itl: " + e.Message
+}
+
+// Validate checks the library for structural problems: playlist items which reference missing
+// tracks, tracks with duplicate PersistentIDs, playlists with missing parents or parents which
+// aren't folders, and a missing or duplicated master playlist.  Returns nil if no problems
+// were found.
+func (l Library) Validate() []ValidationError {
+	var errs []ValidationError
+	add := func(c ValidationCode, format string, args ...interface{}) {
+		errs = append(errs, ValidationError{Code: c, Message: fmt.Sprintf(format, args...)})
+	}
+
+	seen := make(map[string]int)
+	for _, t := range l.sortedTracks() {
+		if t.PersistentID == "" {
+			continue
+		}
+		if id, ok := seen[t.PersistentID]; ok {
+			add(DuplicatePersistentID, "tracks %d and %d have the same persistent ID %s", id, t.TrackID, t.PersistentID)
+			continue
+		}
+		seen[t.PersistentID] = t.TrackID
+	}
+
+	playlists := make(map[string]Playlist, len(l.Playlists))
+	for _, p := range l.Playlists {
+		if _, ok := playlists[p.PlaylistPersistentID]; !ok && p.PlaylistPersistentID != "" {
+			playlists[p.PlaylistPersistentID] = p
+		}
+	}
+
+	masters := 0
+	for _, p := range l.Playlists {
+		if p.Master {
+			masters++
+		}
+		if p.ParentPersistentID != "" {
+			parent, ok := playlists[p.ParentPersistentID]
+			switch {
+			case !ok:
+				add(MissingParent, "playlist %q has missing parent %s", p.Name, p.ParentPersistentID)
+			case !parent.Folder:
+				add(ParentNotFolder, "playlist %q has parent %q which is not a folder", p.Name, parent.Name)
+			}
+		}
+		for _, item := range p.PlaylistItems {
+			if _, ok := l.GetTrack(item.TrackID); !ok {
+				add(MissingTrack, "playlist %q references missing track %d", p.Name, item.TrackID)
+			}
+		}
+	}
+
+	switch {
+	case masters == 0:
+		add(NoMasterPlaylist, "no master playlist")
+	case masters > 1:
+		add(MultipleMasterPlaylists, "%d master playlists", masters)
+	}
+	return errs
+}
+
+// KeyMismatches returns the entries of the Tracks map whose key is not the decimal form
+// of the TrackID of the track it holds, mapping the key to the TrackID.  Lookups by
+// TrackID (such as GetTrack) fail for these tracks.
+func (l Library) KeyMismatches() map[string]int {
+	m := make(map[string]int)
+	for k, t := range l.Tracks {
+		if k != strconv.Itoa(t.TrackID) {
+			m[k] = t.TrackID
+		}
+	}
+	return m
+}
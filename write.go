@@ -0,0 +1,379 @@
+// Copyright 2014, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package itl
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/xml"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// xmlHeader is the preamble written by iTunes at the top of every library file.
+const xmlHeader = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+`
+
+// plistDateFormat is the layout iTunes uses for <date> values.
+const plistDateFormat = "2006-01-02T15:04:05Z"
+
+// requiredKeys are written even when their value is zero.  All other zero-valued
+// fields are omitted from the output, which is what iTunes does.
+var requiredKeys = map[string]bool{
+	"Major Version": true,
+	"Minor Version": true,
+	"Track ID":      true,
+	"Playlist ID":   true,
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// WriteToXML writes the Library l to w as iTunes XML (plist) data which can be re-imported
+// into iTunes.  As in files written by iTunes, empty strings, zero numbers, false booleans
+// and zero times are omitted (with the exception of the version and ID keys): in particular
+// a track which isn't Loved has no Loved key, which iTunes reads back as false, whereas
+// writing <false/> can be imported differently.  Playlist.Visible is the exception: iTunes
+// writes it only when it is false, so it is written when false and omitted when true.  The
+// output is tab-indented in the same layout as iTunes, see WriteToXMLIndent, and
+// WriteToXMLWithOptions can be used to write false booleans.
+func WriteToXML(w io.Writer, l Library) error {
+	return WriteToXMLIndent(w, l, "\t")
+}
+
+// WriteToXMLIndent is like WriteToXML, but indents nested elements with one copy of indent
+// for each level.  An empty indent writes compact output, with no whitespace between
+// elements.  Keys are always written in the same order, so the output for a Library is
+// deterministic: struct fields in declaration order (which follows the order iTunes
+// writes them) followed by any Extra keys sorted lexically, and Tracks sorted numerically
+// by ID.
+func WriteToXMLIndent(w io.Writer, l Library, indent string) error {
+	return WriteToXMLWithOptions(w, l, WriteOptions{Indent: indent})
+}
+
+// WriteOptions configures WriteToXMLWithOptions.
+type WriteOptions struct {
+	// Indent is written once for each level of nesting, see WriteToXMLIndent.
+	Indent string
+
+	// WriteFalse writes boolean fields which are false as <false/>, rather than omitting
+	// them.  iTunes never writes false booleans (it omits the key, and treats a missing
+	// key as false), so this is only useful for software which expects every key.  Fields
+	// with the keepfalse tag option, such as Playlist.Visible, are unaffected: they are
+	// always written when false and omitted when true.
+	WriteFalse bool
+}
+
+// WriteToXMLWithOptions is like WriteToXML, but writes using the given options.  Note that
+// the zero WriteOptions writes compact output.
+func WriteToXMLWithOptions(w io.Writer, l Library, opts WriteOptions) error {
+	e := &encoder{w: bufio.NewWriter(w), prefix: opts.Indent, writeFalse: opts.WriteFalse}
+	if opts.Indent != "" {
+		e.newline = "\n"
+	}
+	e.writeString(xmlHeader)
+	e.writeValue(reflect.ValueOf(l), 0)
+	e.writeString("</plist>\n")
+	if e.err != nil {
+		return e.err
+	}
+	return e.w.Flush()
+}
+
+// WriteStream is like WriteToXML, but writes the tracks received from tracks rather than
+// those in header.Tracks, which is ignored, so that a large library can be written without
+// holding all its tracks in memory.  All the other values (including playlists) are taken
+// from header.  Tracks are written in the order they are received, just after the values
+// which precede Tracks in header, and Playlists are written once tracks is closed.  Each
+// track is stored under the key for its TrackID.  If writing fails then WriteStream returns
+// the error immediately, without receiving the remaining tracks.
+func WriteStream(w io.Writer, header Library, tracks <-chan Track) error {
+	e := &encoder{w: bufio.NewWriter(w), prefix: "\t", newline: "\n", tracks: tracks}
+	e.writeString(xmlHeader)
+	e.writeValue(reflect.ValueOf(header), 0)
+	e.writeString("</plist>\n")
+	if e.err != nil {
+		return e.err
+	}
+	return e.w.Flush()
+}
+
+// encoder writes plist XML in the layout used by iTunes: tab indentation, with
+// keys and scalar values on the same line.
+type encoder struct {
+	w          *bufio.Writer
+	prefix     string
+	newline    string
+	writeFalse bool
+	err        error
+
+	// tracks, when set, provides the tracks written for the Library Tracks field.
+	tracks <-chan Track
+}
+
+// writeTrackStream writes the Tracks key and the dict of the tracks received from e.tracks.
+func (e *encoder) writeTrackStream(depth int) {
+	e.indent(depth)
+	e.writeString("<key>Tracks</key>" + e.newline)
+	e.indent(depth)
+	e.writeString("<dict>" + e.newline)
+	for t := range e.tracks {
+		if e.err != nil {
+			return
+		}
+		e.writeKey(strconv.Itoa(t.TrackID), reflect.ValueOf(t), depth+1)
+	}
+	e.indent(depth)
+	e.writeString("</dict>" + e.newline)
+}
+
+func (e *encoder) writeString(s string) {
+	if e.err != nil {
+		return
+	}
+	_, e.err = e.w.WriteString(s)
+}
+
+func (e *encoder) indent(depth int) {
+	e.writeString(strings.Repeat(e.prefix, depth))
+}
+
+func (e *encoder) escape(s string) {
+	if e.err != nil {
+		return
+	}
+	e.err = xml.EscapeText(e.w, []byte(s))
+}
+
+// writeKey writes the dict key k followed by the value v.
+func (e *encoder) writeKey(k string, v reflect.Value, depth int) {
+	v = indirect(v)
+	if !v.IsValid() {
+		return
+	}
+	e.indent(depth)
+	e.writeString("<key>")
+	e.escape(k)
+	e.writeString("</key>")
+	if isScalar(v) {
+		e.writeScalar(v)
+		e.writeString(e.newline)
+		return
+	}
+	e.writeString(e.newline)
+	e.writeValue(v, depth)
+}
+
+// writeValue writes v on its own line(s) at the given depth.
+func (e *encoder) writeValue(v reflect.Value, depth int) {
+	v = indirect(v)
+	if !v.IsValid() {
+		return
+	}
+	if isScalar(v) {
+		e.indent(depth)
+		e.writeScalar(v)
+		e.writeString(e.newline)
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		e.indent(depth)
+		e.writeString("<dict>" + e.newline)
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" {
+				continue
+			}
+			k := fieldKey(f)
+			if k == "-" {
+				continue
+			}
+			fv := v.Field(i)
+			if e.tracks != nil && fv.Type() == trackMapType {
+				e.writeTrackStream(depth + 1)
+				continue
+			}
+			if keepFalse(f) {
+				if !fv.Bool() {
+					e.writeKey(k, fv, depth+1)
+				}
+				continue
+			}
+			if !requiredKeys[k] && isEmptyValue(fv) && !(e.writeFalse && fv.Kind() == reflect.Bool) {
+				continue
+			}
+			e.writeKey(k, fv, depth+1)
+		}
+		if x := v.FieldByName("Extra"); x.IsValid() && x.Type() == extraType {
+			for _, k := range sortedMapKeys(x) {
+				e.writeKey(k.String(), x.MapIndex(k), depth+1)
+			}
+		}
+		e.indent(depth)
+		e.writeString("</dict>" + e.newline)
+
+	case reflect.Map:
+		e.indent(depth)
+		e.writeString("<dict>" + e.newline)
+		for _, k := range sortedMapKeys(v) {
+			e.writeKey(k.String(), v.MapIndex(k), depth+1)
+		}
+		e.indent(depth)
+		e.writeString("</dict>" + e.newline)
+
+	case reflect.Slice, reflect.Array:
+		e.indent(depth)
+		e.writeString("<array>" + e.newline)
+		for i := 0; i < v.Len(); i++ {
+			e.writeValue(v.Index(i), depth+1)
+		}
+		e.indent(depth)
+		e.writeString("</array>" + e.newline)
+	}
+}
+
+// writeScalar writes the scalar value v without indentation or trailing newline.
+func (e *encoder) writeScalar(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.String:
+		e.writeString("<string>")
+		e.escape(v.String())
+		e.writeString("</string>")
+
+	case reflect.Bool:
+		if v.Bool() {
+			e.writeString("<true/>")
+		} else {
+			e.writeString("<false/>")
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.writeString("<integer>" + strconv.FormatInt(v.Int(), 10) + "</integer>")
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		e.writeString("<integer>" + strconv.FormatUint(v.Uint(), 10) + "</integer>")
+
+	case reflect.Float32, reflect.Float64:
+		e.writeString("<real>" + strconv.FormatFloat(v.Float(), 'g', -1, 64) + "</real>")
+
+	case reflect.Slice:
+		e.writeString("<data>" + base64.StdEncoding.EncodeToString(v.Bytes()) + "</data>")
+
+	case reflect.Struct:
+		t := v.Interface().(time.Time)
+		e.writeString("<date>" + t.UTC().Format(plistDateFormat) + "</date>")
+	}
+}
+
+// isScalar returns true if v is written as a single plist element rather than
+// a <dict> or <array>.
+func isScalar(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Map, reflect.Array:
+		return false
+	case reflect.Slice:
+		return v.Type().Elem().Kind() == reflect.Uint8
+	case reflect.Struct:
+		return v.Type() == timeType
+	}
+	return true
+}
+
+// indirect follows pointers and interfaces, returning the zero Value if it
+// reaches nil.
+func indirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Interface || v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// isEmptyValue returns true if v should be omitted from the output.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.String, reflect.Map, reflect.Slice, reflect.Array:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	case reflect.Struct:
+		if v.Type() == timeType {
+			return v.Interface().(time.Time).IsZero()
+		}
+	}
+	return false
+}
+
+// fieldKey returns the plist key for the struct field f: the value of its plist
+// tag (without any options) if set, otherwise the field name.
+func fieldKey(f reflect.StructField) string {
+	k := f.Tag.Get("plist")
+	if i := strings.Index(k, ","); i >= 0 {
+		k = k[:i]
+	}
+	if k != "" {
+		return k
+	}
+	return f.Name
+}
+
+// keepFalse returns true if f is a bool field with the keepfalse tag option, such as
+// Playlist.Visible.  iTunes writes these keys only when they are false, and a missing key
+// means true: so they decode as true when the key is missing, and are written when false
+// (whatever WriteOptions.WriteFalse says) and omitted when true.
+func keepFalse(f reflect.StructField) bool {
+	if f.Type.Kind() != reflect.Bool {
+		return false
+	}
+	opts := strings.Split(f.Tag.Get("plist"), ",")
+	for _, o := range opts[1:] {
+		if o == "keepfalse" {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedMapKeys returns the keys of the map v in the order they should be written.
+// Keys which are all integers (as in the Tracks dict) are sorted numerically, otherwise
+// they are sorted lexically.
+func sortedMapKeys(v reflect.Value) []reflect.Value {
+	keys := v.MapKeys()
+	numeric := true
+	n := make(map[string]int64, len(keys))
+	for _, k := range keys {
+		i, err := strconv.ParseInt(k.String(), 10, 64)
+		if err != nil {
+			numeric = false
+			break
+		}
+		n[k.String()] = i
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if numeric {
+			return n[keys[i].String()] < n[keys[j].String()]
+		}
+		return keys[i].String() < keys[j].String()
+	})
+	return keys
+}
@@ -0,0 +1,199 @@
+// Copyright 2014, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package itl
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteToXML(t *testing.T) {
+	l := Library{
+		MajorVersion: 1,
+		Date:         time.Date(2014, 6, 22, 11, 38, 11, 0, time.FixedZone("BST", 3600)),
+		Tracks: map[string]Track{
+			"10": {TrackID: 10, Name: `Rock & Roll <"Live">`},
+			"9":  {TrackID: 9},
+		},
+		Playlists: []Playlist{
+			{PlaylistID: 2, Master: true, SmartInfo: []byte{1, 1, 0, 3}},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteToXML(&buf, l); err != nil {
+		t.Fatalf("WriteToXML() error = %v", err)
+	}
+	want := xmlHeader + `<dict>
+	<key>Major Version</key><integer>1</integer>
+	<key>Minor Version</key><integer>0</integer>
+	<key>Date</key><date>2014-06-22T10:38:11Z</date>
+	<key>Tracks</key>
+	<dict>
+		<key>9</key>
+		<dict>
+			<key>Track ID</key><integer>9</integer>
+		</dict>
+		<key>10</key>
+		<dict>
+			<key>Track ID</key><integer>10</integer>
+			<key>Name</key><string>Rock &amp; Roll &lt;&#34;Live&#34;&gt;</string>
+		</dict>
+	</dict>
+	<key>Playlists</key>
+	<array>
+		<dict>
+			<key>Master</key><true/>
+			<key>Playlist ID</key><integer>2</integer>
+			<key>Visible</key><false/>
+			<key>Smart Info</key><data>AQEAAw==</data>
+		</dict>
+	</array>
+</dict>
+</plist>
+`
+	if got := buf.String(); got != want {
+		t.Errorf("WriteToXML() =\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestWriteData(t *testing.T) {
+	v := struct {
+		Data  []byte
+		Empty []byte
+	}{Data: []byte{1, 1, 0, 3}}
+
+	var buf bytes.Buffer
+	e := &encoder{w: bufio.NewWriter(&buf), prefix: "\t", newline: "\n"}
+	e.writeValue(reflect.ValueOf(v), 0)
+	if err := e.w.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	want := "<dict>\n\t<key>Data</key><data>AQEAAw==</data>\n</dict>\n"
+	if got := buf.String(); got != want {
+		t.Errorf("writeValue() = %q, want %q", got, want)
+	}
+}
+
+func TestWriteToXMLRoundTrip(t *testing.T) {
+	l := Library{
+		MajorVersion:       1,
+		MinorVersion:       1,
+		Date:               time.Date(2014, 6, 22, 10, 38, 11, 0, time.UTC),
+		ApplicationVersion: "11.2.2",
+		ShowContentRatings: true,
+		Tracks: map[string]Track{
+			"1": {
+				TrackID:   1,
+				Name:      "Tab\tand\nnewline & 'quotes'",
+				DateAdded: time.Date(2013, 1, 2, 3, 4, 5, 0, time.UTC),
+				Loved:     true,
+				Extra:     map[string]interface{}{"Normalization": int64(1530), "Empty": map[string]interface{}{}},
+			},
+		},
+		Playlists: []Playlist{
+			{Name: "Smart", PlaylistID: 2, SmartCriteria: []byte("criteria"), PlaylistItems: []PlaylistItem{{TrackID: 1}}},
+		},
+		Extra: map[string]interface{}{"Unknown": []interface{}{"a", true, 1.5}},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteToXML(&buf, l); err != nil {
+		t.Fatalf("WriteToXML() error = %v", err)
+	}
+	got, err := ReadFromXML(&buf)
+	if err != nil {
+		t.Fatalf("ReadFromXML() of WriteToXML() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, l) {
+		t.Errorf("ReadFromXML(WriteToXML(l)) = %+v, want %+v", got, l)
+	}
+}
+
+func TestWriteToXMLVisible(t *testing.T) {
+	l, err := Decode(strings.NewReader(testLibraryXML))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if l.Playlists[0].Visible || !l.Playlists[1].Visible {
+		t.Fatalf("Visible = %v, %v, want false for the master playlist, true for the playlist without a Visible key", l.Playlists[0].Visible, l.Playlists[1].Visible)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteToXML(&buf, l); err != nil {
+		t.Fatalf("WriteToXML() error = %v", err)
+	}
+	out := buf.String()
+	master := out[strings.Index(out, "<key>Master</key>"):strings.Index(out, "<string>Loved &amp; Recent</string>")]
+	if want := "\t\t\t<key>Visible</key><false/>\n"; !strings.Contains(master, want) {
+		t.Errorf("WriteToXML() master playlist doesn't contain %q:\n%s", want, master)
+	}
+	if n := strings.Count(out, "<key>Visible</key>"); n != 1 {
+		t.Errorf("WriteToXML() wrote %d Visible keys, want 1 (only for the hidden master playlist)", n)
+	}
+}
+
+// failWriter fails every write.
+type failWriter struct{}
+
+func (failWriter) Write([]byte) (int, error) { return 0, errors.New("write failed") }
+
+func TestWriteToXMLError(t *testing.T) {
+	l := Library{Tracks: map[string]Track{"1": {TrackID: 1, Name: strings.Repeat("x", 8192)}}}
+	if err := WriteToXML(failWriter{}, l); err == nil || err.Error() != "write failed" {
+		t.Errorf("WriteToXML() error = %v, want \"write failed\"", err)
+	}
+}
+
+func TestWriteToXMLFalse(t *testing.T) {
+	l := Library{
+		Tracks: map[string]Track{"1": {TrackID: 1, Loved: false}},
+		Playlists: []Playlist{
+			{Name: "Hidden", Visible: false},
+			{Name: "Shown", Visible: true},
+		},
+	}
+	const visible = "<key>Visible</key><false/>"
+
+	var buf bytes.Buffer
+	if err := WriteToXML(&buf, l); err != nil {
+		t.Fatalf("WriteToXML() error = %v", err)
+	}
+	if strings.Contains(buf.String(), "<key>Loved</key>") {
+		t.Errorf("WriteToXML() wrote a Loved key for a track which isn't Loved:\n%s", buf.String())
+	}
+	if n := strings.Count(buf.String(), "<key>Visible</key>"); n != 1 || !strings.Contains(buf.String(), visible) {
+		t.Errorf("WriteToXML() wrote %d Visible keys, want 1 %q for the hidden playlist:\n%s", n, visible, buf.String())
+	}
+
+	buf.Reset()
+	if err := WriteToXMLWithOptions(&buf, l, WriteOptions{Indent: "\t", WriteFalse: true}); err != nil {
+		t.Fatalf("WriteToXMLWithOptions() error = %v", err)
+	}
+	if want := "\t\t\t<key>Loved</key><false/>\n"; !strings.Contains(buf.String(), want) {
+		t.Errorf("WriteToXMLWithOptions(WriteFalse) output doesn't contain %q:\n%s", want, buf.String())
+	}
+	if n := strings.Count(buf.String(), "<key>Visible</key>"); n != 1 || !strings.Contains(buf.String(), visible) {
+		t.Errorf("WriteToXMLWithOptions(WriteFalse) wrote %d Visible keys, want 1 %q for the hidden playlist:\n%s", n, visible, buf.String())
+	}
+
+	got, err := Decode(&buf)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if got.Tracks["1"].Loved {
+		t.Errorf("Loved = true after writing <false/>, want false")
+	}
+	for i, want := range []bool{false, true} {
+		if got.Playlists[i].Visible != want {
+			t.Errorf("Playlists[%d].Visible = %v, want %v", i, got.Playlists[i].Visible, want)
+		}
+	}
+}